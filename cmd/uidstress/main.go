@@ -2,34 +2,112 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"id-tester/internal/tools"
 	"id-tester/internal/tools/uidstress"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "list":
+			listSchemes()
+			return
+		case "burst":
+			runBurst(os.Args[2:])
+			return
+		case "multiproc":
+			runMultiProc(os.Args[2:])
+			return
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "compare":
+			runCompare(os.Args[2:])
+			return
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		case "scaling":
+			runScaling(os.Args[2:])
+			return
+		case "quality":
+			runQuality(os.Args[2:])
+			return
+		case "calc":
+			runCalc(os.Args[2:])
+			return
+		case "hunt":
+			runHunt(os.Args[2:])
+			return
+		}
+	}
+	runStress(os.Args[1:])
+}
+
+// runStress runs the default stress test command.
+func runStress(args []string) {
+	fs := flag.NewFlagSet("uidstress", flag.ExitOnError)
 	var (
-		schemesFlag     = flag.String("schemes", "nanoid16,ulid,ksuid", "comma separated list of schemes (nanoid16, ulid, ksuid)")
-		scaleFlag       = flag.Int64("scale", 50_000_000, "number of IDs to generate per scheme")
-		chunkFlag       = flag.Int64("chunk", 1_000_000, "number of IDs per chunk")
-		tempDirFlag     = flag.String("tempdir", "", "base directory for temporary chunk files")
-		keepFlag        = flag.Bool("keep", false, "keep temporary data after completion")
-		logIntervalFlag = flag.Int64("log-interval", 1_000_000, "progress log interval")
-		memGuardFlag    = flag.Float64("mem-guard", 512, "minimum free memory (MB) to keep above estimated chunk usage")
-		verboseFlag     = flag.Bool("verbose", false, "enable verbose logging")
-		bytesPerIDFlag  = flag.Int64("bytes-per-id", 64, "approximate bytes per ID for resource estimation")
-		diskFactorFlag  = flag.Float64("disk-factor", 1.25, "disk safety factor multiplier")
+		schemesFlag     = fs.String("schemes", "nanoid16,ulid,ksuid", "comma separated list of schemes (see `uidstress list`), or all")
+		scaleFlag       = fs.Int64("scale", 50_000_000, "number of IDs to generate per scheme")
+		chunkFlag       = fs.Int64("chunk", 1_000_000, "number of IDs per chunk")
+		workersFlag     = fs.Int("workers", runtime.NumCPU(), "number of goroutines generating IDs concurrently per chunk")
+		tempDirFlag     = fs.String("tempdir", "", "base directory for temporary chunk files")
+		keepFlag        = fs.Bool("keep", false, "keep temporary data after completion")
+		logIntervalFlag = fs.Int64("log-interval", 1_000_000, "progress log interval")
+		memGuardFlag    = fs.Float64("mem-guard", 512, "minimum free memory (MB) to keep above estimated chunk usage")
+		verboseFlag     = fs.Bool("verbose", false, "enable verbose logging")
+		bytesPerIDFlag  = fs.Int64("bytes-per-id", 64, "approximate bytes per ID for resource estimation")
+		diskFactorFlag  = fs.Float64("disk-factor", 1.25, "disk safety factor multiplier")
+		nodeIDFlag      = fs.Int64("node-id", 0, "node ID for schemes that embed one (snowflake: datacenter<<5 | worker)")
+		layoutFlag      = fs.String("layout", "", "bit layout for schemes that support it (customuid), e.g. ts=42,unit=ms,node=0,counter=12,random=26")
+		clockFlag       = fs.String("clock", "system", "clock for time-based schemes: system, frozen[:RFC3339], skew:jump=-1s,every=1000000,stall=0s or step:every=1000000,by=1s")
+		resumeFlag      = fs.String("resume", "", "continue an interrupted run from its temp directory (scheme, scale and chunk size come from its manifest)")
+		startAtFlag     = fs.String("start-at", "", "wait until this RFC 3339 instant before generating (used by multiproc to line up processes)")
+		formatFlag      = fs.String("format", "text", "output format: text, json, csv or markdown")
+		outFlag         = fs.String("out", "", "write the results to this file instead of stdout")
+		historyFlag     = fs.String("history", "", "append the results to this JSON-lines history file (see `uidstress compare`)")
+		latencyFlag     = fs.Int64("latency", 0, "time every Nth ID of each worker and report latency percentiles (1 = every ID, 0 = off)")
+		durationFlag    = fs.Duration("duration", 0, "generate each scheme for this long instead of until -scale (-scale still caps it when given)")
+		rateFlag        = fs.Float64("rate", 0, "limit generation to this many IDs per second across all workers (0 = unlimited)")
+		orderFlag       = fs.Bool("order", false, "analyze generation order across workers and lexicographic vs embedded-timestamp order")
+		truncateFlag    = fs.Int("truncate-bits", 0, "keep only the low N bits of each ID's random portion and compare duplicates with the birthday bound (0 = off)")
 	)
-	flag.Parse()
+	fs.Parse(args)
+	format := parseFormat(*formatFlag)
+	scale := *scaleFlag
+	if *durationFlag > 0 && !flagSet(fs, "scale") {
+		scale = 0
+	}
+
+	var startAt time.Time
+	if *startAtFlag != "" {
+		var err error
+		if startAt, err = time.Parse(time.RFC3339Nano, *startAtFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -start-at: %v\n", err)
+			os.Exit(2)
+		}
+	}
 
 	cfg := uidstress.Config{
 		Schemes:          parseSchemes(*schemesFlag),
-		Scale:            *scaleFlag,
+		Scale:            scale,
 		ChunkSize:        *chunkFlag,
+		Workers:          *workersFlag,
 		TempDir:          *tempDirFlag,
 		KeepTempData:     *keepFlag,
 		LogInterval:      *logIntervalFlag,
@@ -37,31 +115,742 @@ func main() {
 		ApproxBytesPerID: *bytesPerIDFlag,
 		MemGuardMB:       *memGuardFlag,
 		DiskSafetyFactor: *diskFactorFlag,
+		NodeID:           *nodeIDFlag,
+		Layout:           *layoutFlag,
+		Clock:            *clockFlag,
+		ResumeDir:        *resumeFlag,
+		StartAt:          startAt,
+		LatencySample:    *latencyFlag,
+		Duration:         *durationFlag,
+		Rate:             *rateFlag,
+		TrackOrder:       *orderFlag,
+		TruncateBits:     *truncateFlag,
 	}
 
-	ctx := context.Background()
+	// Cancel on interrupt so completed chunks are kept for -resume.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	results, err := uidstress.Run(ctx, cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "uidstress failed: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+
+	writeResults(format, *outFlag, *historyFlag, uidstress.Report{
+		Title:   "UID Stress Test Summary",
+		Command: "stress",
+		Config:  cfg,
+		Results: results,
+	})
+}
+
+// writeResults renders rep in format to the -out file, or stdout when out
+// is empty, and appends it to the history file when one is given.
+func writeResults(format uidstress.Format, out, history string, rep uidstress.Report) {
+	rep.CreatedAt = time.Now()
+	rep.Environment = uidstress.CurrentEnvironment()
+
+	if history != "" {
+		runID, err := uidstress.AppendHistory(history, rep)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "append history %s: %v\n", history, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "results recorded in %s as run %s\n", history, runID)
+	}
+
+	w, closeOut := openOutput(out)
+	defer closeOut()
+	if err := uidstress.WriteReport(w, format, rep); err != nil {
+		fmt.Fprintf(os.Stderr, "write results: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// openOutput returns the file named by -out, or stdout when out is empty,
+// and a function that closes it.
+func openOutput(out string) (io.Writer, func()) {
+	if out == "" {
+		return os.Stdout, func() {}
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create %s: %v\n", out, err)
+		os.Exit(1)
+	}
+	return f, func() { f.Close() }
+}
+
+// writeOutput writes the results of a command that has only a text and a
+// JSON form: v as indented JSON, or whatever text prints.
+func writeOutput(format uidstress.Format, out string, v any, text func(w io.Writer)) {
+	w, closeOut := openOutput(out)
+	defer closeOut()
+	if format != uidstress.FormatJSON {
+		text(w)
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "write results: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// parseFormat validates the -format flag before any work is done.
+func parseFormat(raw string) uidstress.Format {
+	format, err := uidstress.ParseFormat(raw)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return format
+}
+
+// parseTextFormat validates the -format flag of a command whose results
+// have only a text and a JSON form (see writeOutput).
+func parseTextFormat(cmd, raw string) uidstress.Format {
+	format := parseFormat(raw)
+	if format != uidstress.FormatText && format != uidstress.FormatJSON {
+		fmt.Fprintf(os.Stderr, "%s supports -format text or json, not %s\n", cmd, format)
+		os.Exit(2)
+	}
+	return format
+}
+
+// runMultiProc spawns several copies of this binary that start in the same
+// second and merges their output to count cross-process collisions.
+func runMultiProc(args []string) {
+	fs := flag.NewFlagSet("uidstress multiproc", flag.ExitOnError)
+	var (
+		procsFlag    = fs.Int("procs", 8, "number of child processes")
+		schemesFlag  = fs.String("schemes", "customuid,nanoid16,ulid,ksuid", "comma separated list of schemes, or all")
+		scaleFlag    = fs.Int64("scale", 100_000, "number of IDs each process generates per scheme")
+		chunkFlag    = fs.Int64("chunk", 1_000_000, "number of IDs per chunk")
+		workersFlag  = fs.Int("workers", 1, "number of goroutines per process")
+		tempDirFlag  = fs.String("tempdir", "", "base directory for the children's chunk files")
+		keepFlag     = fs.Bool("keep", false, "keep the children's data and logs after completion")
+		memGuardFlag = fs.Float64("mem-guard", 512, "minimum free memory (MB) each process keeps above estimated chunk usage")
+		verboseFlag  = fs.Bool("verbose", false, "enable verbose logging")
+		nodeIDFlag   = fs.Int64("node-id", 0, "node ID passed to every process")
+		nodeIDsFlag  = fs.Bool("node-ids", false, "give process i the node ID node-id+i instead of sharing one")
+		layoutFlag   = fs.String("layout", "", "bit layout for schemes that support it (customuid)")
+		clockFlag    = fs.String("clock", "system", "clock for time-based schemes in every process")
+		delayFlag    = fs.Duration("start-delay", 2*time.Second, "time allowed for the processes to start before the common start second")
+		formatFlag   = fs.String("format", "text", "output format: text, json, csv or markdown")
+		outFlag      = fs.String("out", "", "write the results to this file instead of stdout")
+		historyFlag  = fs.String("history", "", "append the results to this JSON-lines history file (see `uidstress compare`)")
+		latencyFlag  = fs.Int64("latency", 0, "time every Nth ID of each worker in every process (0 = off)")
+		durationFlag = fs.Duration("duration", 0, "generate each scheme for this long in every process (-scale still caps it when given)")
+		rateFlag     = fs.Float64("rate", 0, "limit each process to this many IDs per second (0 = unlimited)")
+		orderFlag    = fs.Bool("order", false, "analyze generation order in every process")
+		truncateFlag = fs.Int("truncate-bits", 0, "truncate every process's IDs to the low N random bits (0 = off)")
+	)
+	fs.Parse(args)
+	format := parseFormat(*formatFlag)
+	scale := *scaleFlag
+	if *durationFlag > 0 && !flagSet(fs, "scale") {
+		scale = 0
+	}
+
+	cfg := uidstress.MultiProcConfig{
+		Config: uidstress.Config{
+			Schemes:       parseSchemes(*schemesFlag),
+			Scale:         scale,
+			ChunkSize:     *chunkFlag,
+			Workers:       *workersFlag,
+			TempDir:       *tempDirFlag,
+			KeepTempData:  *keepFlag,
+			Verbose:       *verboseFlag,
+			MemGuardMB:    *memGuardFlag,
+			NodeID:        *nodeIDFlag,
+			Layout:        *layoutFlag,
+			Clock:         *clockFlag,
+			LatencySample: *latencyFlag,
+			Duration:      *durationFlag,
+			Rate:          *rateFlag,
+			TrackOrder:    *orderFlag,
+			TruncateBits:  *truncateFlag,
+		},
+		Procs:           *procsFlag,
+		DistinctNodeIDs: *nodeIDsFlag,
+		StartDelay:      *delayFlag,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	results, err := uidstress.MultiProc(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress multiproc failed: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+	writeResults(format, *outFlag, *historyFlag, uidstress.Report{
+		Title:   fmt.Sprintf("UID Multi-Process Summary (%d processes)", cfg.Procs),
+		Command: "multiproc",
+		Config:  cfg,
+		Results: results,
+	})
+}
+
+// runMerge merges the kept runs named on the command line, e.g. runs from
+// several machines, and reports global unique and duplicate counts.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("uidstress merge", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uidstress merge [flags] <manifest.json or run dir>...")
+		fs.PrintDefaults()
+	}
+	var (
+		reportDirFlag   = fs.String("report-dir", ".", "directory for the merge-<scheme>-duplicates.ndjson reports")
+		verboseFlag     = fs.Bool("verbose", false, "enable verbose logging")
+		logIntervalFlag = fs.Int64("log-interval", 1_000_000, "progress log interval")
+		formatFlag      = fs.String("format", "text", "output format: text, json, csv or markdown")
+		outFlag         = fs.String("out", "", "write the results to this file instead of stdout")
+		historyFlag     = fs.String("history", "", "append the results to this JSON-lines history file (see `uidstress compare`)")
+	)
+	fs.Parse(args)
+	format := parseFormat(*formatFlag)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg := uidstress.MergeConfig{
+		Manifests:   fs.Args(),
+		ReportDir:   *reportDirFlag,
+		Verbose:     *verboseFlag,
+		LogInterval: *logIntervalFlag,
+	}
+	results, err := uidstress.Merge(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress merge failed: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+	writeResults(format, *outFlag, *historyFlag, uidstress.Report{
+		Title:   "UID Merge Summary",
+		Command: "merge",
+		Config:  cfg,
+		Results: results,
+	})
+}
+
+// runCompare compares the throughput of two runs from the history file and
+// exits with status 1 when a scheme regressed beyond the tolerance.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("uidstress compare", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uidstress compare [flags] [baseline-run [run]]")
+		fmt.Fprintln(fs.Output(), "Without arguments the latest run is compared with the previous matching run of each scheme.")
+		fs.PrintDefaults()
+	}
+	var (
+		historyFlag   = fs.String("history", "uidstress-history.jsonl", "JSON-lines history file written with -history")
+		toleranceFlag = fs.Float64("tolerance", 5, "accepted throughput drop in percent")
+	)
+	fs.Parse(args)
+	if fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	baseline, run := fs.Arg(0), fs.Arg(1)
+
+	records, err := uidstress.LoadHistory(*historyFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress compare failed: %v\n", err)
+		os.Exit(2)
+	}
+	comparisons, err := uidstress.Compare(records, baseline, run, *toleranceFlag/100)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress compare failed: %v\n", err)
+		os.Exit(2)
+	}
+
+	regressed := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCHEME\tBASELINE RUN\tRUN\tBASELINE IDS/S\tIDS/S\tCHANGE\tDUPLICATES\tSTATUS")
+	for _, c := range comparisons {
+		status := "ok"
+		if c.Regressed {
+			status = "REGRESSED"
+			regressed = true
+		}
+		if c.Mismatch != "" {
+			status += " (" + c.Mismatch + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.0f\t%.0f\t%+.1f%%\t%d -> %d\t%s\n",
+			c.Scheme, c.Baseline.RunID, c.Current.RunID, c.Baseline.Throughput(), c.Current.Throughput(),
+			c.Change*100, c.Baseline.Result.TotalDuplicates, c.Current.Result.TotalDuplicates, status)
+	}
+	w.Flush()
+	if regressed {
+		os.Exit(1)
+	}
+}
+
+// runBench benchmarks Generate for each scheme and reports whether the
+// schemes differ significantly.
+func runBench(args []string) {
+	fs := flag.NewFlagSet("uidstress bench", flag.ExitOnError)
+	var (
+		schemesFlag    = fs.String("schemes", "all", "comma separated list of schemes, or all")
+		warmupFlag     = fs.Duration("warmup", 500*time.Millisecond, "warmup time per scheme, also used to size repetitions")
+		repsFlag       = fs.Int("reps", 10, "measured repetitions per scheme")
+		repTimeFlag    = fs.Duration("rep-time", 500*time.Millisecond, "target duration of one repetition")
+		goroutinesFlag = fs.Int("goroutines", 1, "goroutines generating concurrently in each repetition")
+		alphaFlag      = fs.Float64("alpha", 0.05, "significance level for the pairwise comparisons (Bonferroni-corrected across pairs)")
+		nodeIDFlag     = fs.Int64("node-id", 0, "node ID for schemes that embed one")
+		layoutFlag     = fs.String("layout", "", "bit layout for schemes that support it (customuid)")
+		formatFlag     = fs.String("format", "text", "output format: text, json or csv (csv has the per-scheme results only)")
+		outFlag        = fs.String("out", "", "write the results to this file instead of stdout")
+	)
+	fs.Parse(args)
+	format := parseFormat(*formatFlag)
+	if format == uidstress.FormatMarkdown {
+		fmt.Fprintf(os.Stderr, "bench supports -format text, json or csv, not %s\n", format)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg := uidstress.BenchConfig{
+		Schemes:        parseSchemes(*schemesFlag),
+		Warmup:         *warmupFlag,
+		Repetitions:    *repsFlag,
+		RepetitionTime: *repTimeFlag,
+		Goroutines:     *goroutinesFlag,
+		Alpha:          *alphaFlag,
+		NodeID:         *nodeIDFlag,
+		Layout:         *layoutFlag,
+	}
+	results, comparisons, err := uidstress.Bench(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress bench failed: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+
+	if format == uidstress.FormatCSV {
+		w, closeOut := openOutput(*outFlag)
+		defer closeOut()
+		if err := uidstress.WriteBenchCSV(w, results); err != nil {
+			fmt.Fprintf(os.Stderr, "write results: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	writeOutput(format, *outFlag, struct {
+		Config      uidstress.BenchConfig       `json:"config"`
+		Results     []uidstress.BenchResult     `json:"results"`
+		Comparisons []uidstress.BenchComparison `json:"comparisons"`
+	}{cfg, results, comparisons}, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SCHEME\tOPS/REP\tMETRIC\tMEAN\t95% CI\tMEDIAN\tSTDDEV")
+		for _, res := range results {
+			for i, m := range []struct {
+				name string
+				s    uidstress.Summary
+				prec int
+			}{{"ns/op", res.NsPerOp, 1}, {"B/op", res.BytesPerOp, 1}, {"allocs/op", res.AllocsPerOp, 2}} {
+				scheme, ops := "", ""
+				if i == 0 {
+					scheme, ops = res.Scheme, strconv.FormatInt(res.Ops, 10)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%.*f\t[%.*f, %.*f]\t%.*f\t%.*f\n", scheme, ops, m.name,
+					m.prec, m.s.Mean, m.prec, m.s.CILow, m.prec, m.s.CIHigh, m.prec, m.s.Median, m.prec, m.s.StdDev)
+			}
+		}
+		tw.Flush()
+		if len(comparisons) == 0 {
+			return
+		}
+
+		fmt.Fprintf(w, "\nPairwise ns/op differences (Welch's t-test, alpha %g Bonferroni-corrected across %d pairs):\n", *alphaFlag, len(comparisons))
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FASTER\tSLOWER\tRATIO\tP-VALUE\tSIGNIFICANT")
+		for _, c := range comparisons {
+			fmt.Fprintf(tw, "%s\t%s\t%.3fx\t%.3g\t%t\n", c.Faster, c.Slower, c.Ratio, c.P, c.Significant)
+		}
+		tw.Flush()
+	})
+}
+
+// runScaling measures each scheme's throughput at increasing goroutine
+// counts and prints IDs/sec and efficiency per count.
+func runScaling(args []string) {
+	fs := flag.NewFlagSet("uidstress scaling", flag.ExitOnError)
+	var (
+		schemesFlag    = fs.String("schemes", "all", "comma separated list of schemes, or all")
+		goroutinesFlag = fs.String("goroutines", "", "comma separated goroutine counts (default: 1, 2, 4, ... up to -max)")
+		maxFlag        = fs.Int("max", runtime.NumCPU(), "largest goroutine count when -goroutines is not given")
+		durationFlag   = fs.Duration("duration", time.Second, "generation time per scheme and goroutine count")
+		warmupFlag     = fs.Duration("warmup", 200*time.Millisecond, "single-threaded warmup per scheme")
+		gomaxprocsFlag = fs.Bool("gomaxprocs", false, "set GOMAXPROCS to the goroutine count for each measurement")
+		nodeIDFlag     = fs.Int64("node-id", 0, "node ID for schemes that embed one")
+		layoutFlag     = fs.String("layout", "", "bit layout for schemes that support it (customuid)")
+	)
+	fs.Parse(args)
+
+	var counts []int
+	for _, part := range parseSchemes(*goroutinesFlag) {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -goroutines: %v\n", err)
+			os.Exit(2)
+		}
+		counts = append(counts, n)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	results, err := uidstress.Scaling(ctx, uidstress.ScalingConfig{
+		Schemes:        parseSchemes(*schemesFlag),
+		Goroutines:     counts,
+		MaxGoroutines:  *maxFlag,
+		Duration:       *durationFlag,
+		Warmup:         *warmupFlag,
+		VaryGOMAXPROCS: *gomaxprocsFlag,
+		NodeID:         *nodeIDFlag,
+		Layout:         *layoutFlag,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress scaling failed: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	header := "SCHEME"
+	for _, p := range results[0].Points {
+		header += fmt.Sprintf("\t%d", p.Goroutines)
+		if *gomaxprocsFlag {
+			header += fmt.Sprintf(" (P=%d)", p.GOMAXPROCS)
+		}
+	}
+	fmt.Println("IDs/sec by goroutine count:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, header+"\t")
+	for _, res := range results {
+		fmt.Fprint(w, res.Scheme)
+		for _, p := range res.Points {
+			fmt.Fprintf(w, "\t%.0f", p.Rate)
+		}
+		fmt.Fprintln(w, "\t")
+	}
+	w.Flush()
+
+	fmt.Println("\nEfficiency (speedup / goroutines, 1.00 = linear):")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, header+"\t")
+	for _, res := range results {
+		fmt.Fprint(w, res.Scheme)
+		for _, p := range res.Points {
+			fmt.Fprintf(w, "\t%.2f", p.Efficiency)
+		}
+		fmt.Fprintln(w, "\t")
+	}
+	w.Flush()
+}
+
+// runQuality tests the character distribution of every position of each
+// scheme's IDs and flags biased positions.
+func runQuality(args []string) {
+	fs := flag.NewFlagSet("uidstress quality", flag.ExitOnError)
+	var (
+		schemesFlag = fs.String("schemes", "all", "comma separated list of schemes, or all")
+		samplesFlag = fs.Int64("samples", 1_000_000, "number of IDs sampled per scheme")
+		alphaFlag   = fs.Float64("alpha", 0.01, "significance level per scheme (Bonferroni-corrected across positions)")
+		nodeIDFlag  = fs.Int64("node-id", 0, "node ID for schemes that embed one")
+		layoutFlag  = fs.String("layout", "", "bit layout for schemes that support it (customuid)")
+		formatFlag  = fs.String("format", "text", "output format: text or json (json includes every character count)")
+		outFlag     = fs.String("out", "", "write the results to this file instead of stdout")
+	)
+	fs.Parse(args)
+	format := parseTextFormat("quality", *formatFlag)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg := uidstress.QualityConfig{
+		Schemes: parseSchemes(*schemesFlag),
+		Samples: *samplesFlag,
+		Alpha:   *alphaFlag,
+		NodeID:  *nodeIDFlag,
+		Layout:  *layoutFlag,
+	}
+	results, err := uidstress.Quality(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress quality failed: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+
+	writeOutput(format, *outFlag, struct {
+		Config  uidstress.QualityConfig   `json:"config"`
+		Results []uidstress.QualityResult `json:"results"`
+	}{cfg, results}, func(w io.Writer) {
+		for _, res := range results {
+			fmt.Fprintf(w, "%s: %d samples, charset %d chars, entropy %.1f bits (claimed random %.1f), flag p < %.2g: %s\n",
+				res.Scheme, res.Samples, len(res.Charset), res.EntropyBits, res.ClaimedBits, res.Threshold, res.Summary())
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "POS\tALPHABET\tCHI-SQUARE\tDF\tP-VALUE\tENTROPY\tMAX\tSTATUS")
+			for _, pq := range res.Positions {
+				if pq.Fixed {
+					for ch := range pq.Counts {
+						fmt.Fprintf(tw, "%d\t-\t-\t-\t-\t%.3f\t-\tfixed %q\n", pq.Position, pq.Entropy, ch)
+					}
+					continue
+				}
+				status := "ok"
+				switch {
+				case pq.Biased:
+					status = "BIASED"
+				case pq.TooUniform:
+					status = "TOO UNIFORM"
+				}
+				if pq.Foreign > 0 {
+					status += fmt.Sprintf(" (%d outside charset)", pq.Foreign)
+				}
+				fmt.Fprintf(tw, "%d\t%d\t%.1f\t%d\t%.3g\t%.3f\t%.3f\t%s\n",
+					pq.Position, pq.Alphabet, pq.ChiSquare, pq.DF, pq.P, pq.Entropy, pq.MaxEntropy, status)
+			}
+			tw.Flush()
+			fmt.Fprintln(w)
+		}
+	})
+}
+
+// runCalc prints the collision odds of each scheme for a deployment,
+// computed from the bit layouts alone.
+func runCalc(args []string) {
+	fs := flag.NewFlagSet("uidstress calc", flag.ExitOnError)
+	var (
+		schemesFlag  = fs.String("schemes", "all", "comma separated list of schemes, or all")
+		rateFlag     = fs.Float64("rate", 1000, "IDs generated per second by each node")
+		nodesFlag    = fs.Int("nodes", 1, "number of nodes generating at the same time")
+		durationFlag = fs.Duration("duration", 24*time.Hour, "period to compute the collision probability for")
+		targetFlag   = fs.Float64("target", 0.01, "collision probability to compute the time to")
+		nodeIDsFlag  = fs.Bool("node-ids", false, "assume every node has its own node ID instead of sharing the default one")
+		layoutFlag   = fs.String("layout", "", "bit layout for schemes that support it (customuid)")
+		formatFlag   = fs.String("format", "text", "output format: text or json")
+		outFlag      = fs.String("out", "", "write the results to this file instead of stdout")
+	)
+	fs.Parse(args)
+	format := parseTextFormat("calc", *formatFlag)
+
+	cfg := uidstress.CalcConfig{
+		Schemes:         parseSchemes(*schemesFlag),
+		Rate:            *rateFlag,
+		Nodes:           *nodesFlag,
+		Duration:        *durationFlag,
+		Target:          *targetFlag,
+		DistinctNodeIDs: *nodeIDsFlag,
+		Layout:          *layoutFlag,
+	}
+	results, err := uidstress.Calc(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress calc failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeOutput(format, *outFlag, struct {
+		Config  uidstress.CalcConfig   `json:"config"`
+		Results []uidstress.CalcResult `json:"results"`
+	}{cfg, results}, func(w io.Writer) {
+		nodeIDs := "all nodes share one node ID (pass -node-ids if each node is configured with its own)"
+		if cfg.DistinctNodeIDs {
+			nodeIDs = "each node has its own node ID (-node-ids)"
+		}
+		fmt.Fprintf(w, "%d node(s) x %g IDs/sec for %s\nAssuming %s\n\n", cfg.Nodes, cfg.Rate, cfg.Duration, nodeIDs)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "SCHEME\tBUCKET\tIDS/BUCKET\tP(COLLISION)\tEXP. DUPLICATES\tP W/O BUCKETS\tMEAN TIME TO 1ST\tTIME TO P=%g\tNOTE\n", cfg.Target)
+		for _, res := range results {
+			bucket, unbucketed := "-", "-"
+			if res.Bucket > 0 {
+				bucket = res.Bucket.String()
+			}
+			if res.UnbucketedProbability > 0 {
+				unbucketed = fmt.Sprintf("%.3g", res.UnbucketedProbability)
+			}
+			mean, target := "never", "never"
+			if !res.Impossible {
+				mean, target = uidstress.FormatSeconds(res.MeanTimeToCollision), uidstress.FormatSeconds(res.TimeToTarget)
+			}
+			var notes []string
+			if res.Impossible {
+				notes = append(notes, "node IDs never shared")
+			}
+			if res.NodeBits > 0 && cfg.DistinctNodeIDs && float64(cfg.Nodes) > math.Exp2(float64(res.NodeBits)) {
+				notes = append(notes, fmt.Sprintf("more nodes than %d-bit node IDs", res.NodeBits))
+			}
+			if res.CounterOverflow {
+				notes = append(notes, "counter overflows")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.4g\t%.3g\t%.3g\t%s\t%s\t%s\t%s\n", res.Scheme, bucket, res.IDsPerBucket,
+				res.Probability, res.ExpectedDuplicates, unbucketed, mean, target, strings.Join(notes, ", "))
+		}
+		tw.Flush()
+	})
+}
+
+// runHunt generates IDs of a small ID space until the first duplicate,
+// over repeated trials, and compares the counts with the birthday bound.
+func runHunt(args []string) {
+	fs := flag.NewFlagSet("uidstress hunt", flag.ExitOnError)
+	var (
+		schemeFlag   = fs.String("scheme", "nanoid", "scheme to hunt collisions in")
+		lengthFlag   = fs.Int("length", 6, "nanoid length (only used for nanoid)")
+		layoutFlag   = fs.String("layout", "", "bit layout for schemes that support it, e.g. counter=4,random=16 (customuid)")
+		nodeIDFlag   = fs.Int64("node-id", 0, "node ID for schemes that embed one")
+		clockFlag    = fs.String("clock", "frozen", "clock for time-based schemes: frozen keeps every ID in one time unit, as the expectation assumes")
+		trialsFlag   = fs.Int("trials", 100, "number of hunts")
+		maxIDsFlag   = fs.Int64("max-ids", 10_000_000, "give up a trial without a duplicate after this many IDs")
+		memGuardFlag = fs.Float64("mem-guard", 512, "minimum free memory (MB) to keep above estimated usage")
+		verboseFlag  = fs.Bool("verbose", false, "print every trial")
+		formatFlag   = fs.String("format", "text", "output format: text or json")
+		outFlag      = fs.String("out", "", "write the results to this file instead of stdout")
+	)
+	fs.Parse(args)
+	format := parseTextFormat("hunt", *formatFlag)
+
+	cfg := uidstress.HuntConfig{
+		Scheme:     *schemeFlag,
+		NodeID:     *nodeIDFlag,
+		Layout:     *layoutFlag,
+		Clock:      *clockFlag,
+		Trials:     *trialsFlag,
+		MaxIDs:     *maxIDsFlag,
+		MemGuardMB: *memGuardFlag,
+		Verbose:    *verboseFlag,
+	}
+	var isNanoid bool
+	if g, err := tools.Lookup(cfg.Scheme); err == nil {
+		isNanoid = g.Name() == "nanoid16"
+	}
+	switch {
+	case flagSet(fs, "length") && !isNanoid:
+		fmt.Fprintf(os.Stderr, "-length only applies to nanoid, not %s\n", cfg.Scheme)
+		os.Exit(2)
+	case isNanoid:
+		cfg.Length = *lengthFlag
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	res, err := uidstress.Hunt(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress hunt failed: %v\n", err)
+		stop()
 		os.Exit(1)
 	}
 
-	fmt.Println("UID Stress Test Summary")
-	fmt.Println(strings.Repeat("=", 72))
+	writeOutput(format, *outFlag, struct {
+		Config uidstress.HuntConfig `json:"config"`
+		Result uidstress.HuntResult `json:"result"`
+	}{cfg, res}, func(w io.Writer) {
+		fmt.Fprintf(w, "%s: 2^%.2f values per draw, %d ID(s) per draw, %d trials in %s\n",
+			res.Scheme, res.SpaceBits, res.IDsPerDraw, res.Trials, res.Duration.Round(time.Millisecond))
+		if !res.SingleTimeUnit {
+			fmt.Fprintf(w, "warning: clock %q lets IDs span several time units, the expectation below does not apply\n", cfg.Clock)
+		}
+		if res.Censored > 0 {
+			fmt.Fprintf(w, "censored:  %d trial(s) without a duplicate in %d IDs, left out below\n", res.Censored, cfg.MaxIDs)
+		}
+		if len(res.Counts) == 0 {
+			return
+		}
+		obs := res.Observed
+		fmt.Fprintf(w, "observed:  mean %.1f (95%% CI %.1f-%.1f), median %.0f, stddev %.1f, min %d, max %d\n",
+			obs.Mean, obs.CILow, obs.CIHigh, obs.Median, obs.StdDev, res.Min, res.Max)
+		fmt.Fprintf(w, "expected:  mean %.1f, stddev %.1f (sqrt(πM/2) = %.1f)\n", res.Expected, res.ExpectedStdDev, res.SqrtApprox)
+		fmt.Fprintf(w, "KS test:   D = %.4f, p = %.3g\n\n", res.KS, res.KSP)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DECILE\tIDS UP TO\tOBSERVED\tEXPECTED")
+		for i, bin := range res.Deciles {
+			upper := "-"
+			if bin.Upper > 0 {
+				upper = strconv.FormatInt(bin.Upper, 10)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%.1f\n", i+1, upper, bin.Observed, bin.Expected)
+		}
+		tw.Flush()
+	})
+}
+
+// runBurst offers customuid a fixed number of IDs per simulated tick and
+// compares how each counter-overflow strategy copes.
+func runBurst(args []string) {
+	fs := flag.NewFlagSet("uidstress burst", flag.ExitOnError)
+	var (
+		layoutFlag   = fs.String("layout", "", "base customuid layout, e.g. counter=12,random=26 (overflow is set per strategy)")
+		overflowFlag = fs.String("overflow", "reroll,wait,borrow,error", "comma separated overflow strategies to compare")
+		perTickFlag  = fs.Int64("per-tick", 200_000, "IDs requested per clock tick (the default layout allows 65535 per second)")
+		ticksFlag    = fs.Int64("ticks", 5, "number of clock ticks the burst lasts")
+		frozenFlag   = fs.Bool("frozen", false, "freeze the clock so the whole burst lands in one tick")
+		workersFlag  = fs.Int("workers", runtime.NumCPU(), "number of goroutines generating IDs concurrently")
+		memGuardFlag = fs.Float64("mem-guard", 512, "minimum free memory (MB) to keep above estimated usage")
+	)
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	results, err := uidstress.Burst(ctx, uidstress.BurstConfig{
+		Layout:     *layoutFlag,
+		Strategies: parseSchemes(*overflowFlag),
+		PerTick:    *perTickFlag,
+		Ticks:      *ticksFlag,
+		Frozen:     *frozenFlag,
+		Workers:    *workersFlag,
+		MemGuardMB: *memGuardFlag,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uidstress burst failed: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OVERFLOW\tREQUESTED\tGENERATED\tERRORS\tDUPLICATES\tCOLLISION RATE\tDRIFT\tTICKS\tDURATION\tNOTE")
 	for _, res := range results {
-		fmt.Printf("Scheme:        %s\n", res.Scheme)
-		fmt.Printf("Duration:      %s\n", res.Duration.Round(time.Millisecond))
-		fmt.Printf("Chunks:        %d\n", res.Chunks)
-		fmt.Printf("Generated:     %d\n", res.Generated)
-		fmt.Printf("Chunk Unique:  %d\n", res.ChunkUnique)
-		fmt.Printf("Unique:        %d\n", res.Unique)
-		fmt.Printf("Duplicates:    %d\n", res.Duplicates)
-		if cfg.KeepTempData {
-			fmt.Printf("Manifest:      %s\n", res.ManifestPath)
-			fmt.Printf("Temp Dir:      %s\n", res.OutputDir)
-		}
-		fmt.Println(strings.Repeat("-", 72))
+		note := ""
+		if res.Err != nil {
+			note = res.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.6f\t%s\t%d\t%s\t%s\n",
+			res.Strategy, res.Requested, res.Generated, res.Errors, res.Duplicates,
+			res.CollisionRate, res.Drift, res.Ticks, res.Duration.Round(time.Millisecond), note)
+	}
+	w.Flush()
+}
+
+// listSchemes prints every registered scheme with its metadata.
+func listSchemes() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCHEME\tLENGTH\tSORTABLE\tENTROPY BITS\tCHARSET")
+	for _, g := range tools.Generators() {
+		meta := g.Meta()
+		length := "var"
+		if meta.Length > 0 {
+			length = fmt.Sprint(meta.Length)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%.1f\t%s\n", g.Name(), length, meta.Sortable, meta.EntropyBits, meta.Charset)
 	}
+	w.Flush()
 }
 
 func parseSchemes(raw string) []string {
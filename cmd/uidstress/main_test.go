@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"id-tester/internal/tools/uidstress"
+)
+
+// TestMain runs main with the arguments in UIDSTRESS_TEST_ARGS (one per
+// line) instead of the tests, so that runMain can check exit statuses.
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv("UIDSTRESS_TEST_ARGS"); ok {
+		os.Args = append([]string{"uidstress"}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs uidstress with args in a child process and returns its exit
+// status and output.
+func runMain(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "UIDSTRESS_TEST_ARGS="+strings.Join(args, "\n"))
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), string(out)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0, string(out)
+}
+
+// TestCompareExitStatus checks that compare exits with 1 when a scheme
+// slowed down beyond the tolerance, 0 when it did not and 2 on errors.
+func TestCompareExitStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, rec := range []struct {
+		run string
+		ids int64
+	}{{"base", 1000}, {"run", 900}} {
+		err := enc.Encode(uidstress.HistoryRecord{
+			RunID:       rec.run,
+			Command:     "stress",
+			Scheme:      "ulid",
+			Scale:       1000,
+			Fingerprint: "f1",
+			Result:      uidstress.Result{Scheme: "ulid", Generated: rec.ids, GenerateTime: time.Second},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"compare", "-history", path}, 1},
+		{[]string{"compare", "-history", path, "-tolerance", "15"}, 0},
+		{[]string{"compare", "-history", path, "run", "base"}, 0},
+		{[]string{"compare", "-history", filepath.Join(t.TempDir(), "missing.jsonl")}, 2},
+	}
+	for _, tt := range tests {
+		if got, out := runMain(t, tt.args...); got != tt.want {
+			t.Errorf("uidstress %s exited with %d, want %d:\n%s", strings.Join(tt.args, " "), got, tt.want, out)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package tools
+
+import "time"
+
+// Clock 时间源，基于时间的生成器通过它获取当前时间，便于测试时钟回拨、跳变和冻结
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 系统时钟
+type SystemClock struct{}
+
+// Now 实现 Clock
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// ClockFunc 将普通函数适配为 Clock
+type ClockFunc func() time.Time
+
+// Now 实现 Clock
+func (f ClockFunc) Now() time.Time { return f() }
+
+// ClockConfigurable 由基于时间的方案实现，返回使用指定时钟的新生成器
+type ClockConfigurable interface {
+	WithClock(c Clock) (Generator, error)
+}
@@ -3,175 +3,746 @@ package tools
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 // CustomUID 16 字符版本的 ULID
 // 设计思路（参考 ULID）：
-// - 前 10 字符（50 位）：秒级时间戳（从 2025-10-01 开始，可表示约 35,000,000 年）
-// - 后 6 字符（30 位）：计数器（16 位）+ 随机数（14 位）
-// - 总计 80 位，使用 Base32 编码为 16 字符
+// - 默认布局共 80 位，使用 Base32 编码为 16 字符，从高到低依次为：
+//   - 34 位秒级时间戳（从 2025-10-01 开始，可表示约 544 年）
+//   - 16 位节点 ID（默认为 0，早期版本中这 16 位恒为 0，默认布局与其逐字节兼容）
+//   - 16 位计数器（同一秒内递增）+ 14 位随机数（每秒更新一次）
 // - 相比标准 ULID：长度更短（16 vs 26），但保持相同的设计思路和排序特性
 // - 使用线程安全的计数器确保在同一秒内也能保证唯一性，避免等待
 // - 性能：单线程约 103.9 ns/op，并发约 209.8 ns/op
+//
+// 位布局可以通过 NewCustomUIDGenerator 的选项调整（基准点、时间精度、各字段位数、总长度），
+// 用于探索更短或随机性更高的变体
+//
+// 同一时间单位内计数器用尽时，默认策略（CustomUIDOverflowReroll）重新生成随机基数并从 1 开始计数，
+// 新的随机基数只有 2^RandomBits 种取值，可能重复发出本时间单位内已生成的 ID；
+// 需要严格唯一时可以选择等待、借用下一个时间单位或返回错误（见 CustomUIDOverflow）
 
 const (
-	// customUIDTimestampBits 时间戳位数（秒级，50 位）
-	customUIDTimestampBits = 50
-	// customUIDCounterBits 计数器位数（16 位，可表示 65536 个序列）
+	// customUIDTimestampBits 默认时间戳位数（秒级，34 位）
+	customUIDTimestampBits = 34
+	// customUIDNodeBits 默认节点 ID 位数（16 位）
+	customUIDNodeBits = 16
+	// customUIDCounterBits 默认计数器位数（16 位，可表示 65536 个序列）
 	customUIDCounterBits = 16
-	// customUIDRandomBits 随机数位数（14 位）
+	// customUIDRandomBits 默认随机数位数（14 位）
 	customUIDRandomBits = 14
-	// customUIDTotalBits 总位数
+	// customUIDTotalBits 默认总位数
 	customUIDTotalBits = 80
-	// customUIDEpoch 自定义时间戳基准点（2025-10-01 00:00:00 UTC）
-	// 使用 2025-10-01 作为基准，50 位秒级时间戳可以表示约 35,000,000 年
-	customUIDEpoch = 1759248000 // 2025-10-01 00:00:00 UTC 的秒数
-	// customUIDMaxCounter 最大计数器值（2^16 - 1）
-	customUIDMaxCounter = 65535
+	// customUIDEpoch 默认时间戳基准点（2025-10-01 00:00:00 UTC 的秒数）
+	customUIDEpoch = 1759248000
+	// customUIDMaxBits 布局最多支持的总位数
+	customUIDMaxBits = 128
 )
 
 // base32Chars Crockford's Base32 字符集（与 ULID 相同）
 const base32Chars = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
 
 var (
-	// customUIDState 用于在同一秒内生成唯一 ID 的状态
-	customUIDState struct {
-		sync.Mutex
-		lastSecond int64
-		counter    uint32 // 计数器，在同一秒内递增
-		randomBase uint32 // 随机基数，每秒更新一次
-	}
+	// ErrCustomUIDLength CustomUID 长度不正确
+	ErrCustomUIDLength = errors.New("customuid: invalid length")
+	// ErrCustomUIDCharset CustomUID 包含 Crockford Base32 以外的字符
+	ErrCustomUIDCharset = errors.New("customuid: invalid character")
+	// ErrCustomUIDOverflow CustomUID 解码后的值超出布局的总位数
+	ErrCustomUIDOverflow = errors.New("customuid: value overflows layout")
+	// ErrCustomUIDLayout CustomUID 布局配置不合法
+	ErrCustomUIDLayout = errors.New("customuid: invalid layout")
+	// ErrCustomUIDCounterExhausted 计数器在当前时间单位内已用尽（CustomUIDOverflowError 策略）
+	ErrCustomUIDCounterExhausted = errors.New("customuid: counter exhausted")
+	// ErrCustomUIDClockStalled 计数器用尽后时钟没有进入下一个时间单位（CustomUIDOverflowWait 策略）
+	ErrCustomUIDClockStalled = errors.New("customuid: clock did not advance")
+)
+
+// CustomUIDOverflow 同一时间单位内计数器用尽时的处理策略
+type CustomUIDOverflow int
+
+const (
+	// CustomUIDOverflowReroll 重新生成随机基数并从 1 开始计数（默认，与早期版本相同），可能产生重复
+	CustomUIDOverflowReroll CustomUIDOverflow = iota
+	// CustomUIDOverflowWait 等待时钟进入下一个时间单位，等待超过一个时间单位加 100ms 时返回 ErrCustomUIDClockStalled
+	CustomUIDOverflowWait
+	// CustomUIDOverflowBorrow 借用下一个时间单位的时间戳，持续突发时时间戳会暂时领先于时钟
+	CustomUIDOverflowBorrow
+	// CustomUIDOverflowError 返回 ErrCustomUIDCounterExhausted，直到时钟进入下一个时间单位
+	CustomUIDOverflowError
 )
 
-// GenerateCustomUID 生成 16 字符版本的 ULID
+// customUIDOverflowNames 各策略的名称，与 ParseCustomUIDOverflow 接受的值一致
+var customUIDOverflowNames = [...]string{"reroll", "wait", "borrow", "error"}
+
+// String 返回策略名称
+func (o CustomUIDOverflow) String() string {
+	if o >= 0 && int(o) < len(customUIDOverflowNames) {
+		return customUIDOverflowNames[o]
+	}
+	return "CustomUIDOverflow(" + strconv.Itoa(int(o)) + ")"
+}
+
+// ParseCustomUIDOverflow 按名称解析计数器溢出策略（reroll、wait、borrow、error）
+func ParseCustomUIDOverflow(name string) (CustomUIDOverflow, error) {
+	for i, n := range customUIDOverflowNames {
+		if strings.EqualFold(name, n) {
+			return CustomUIDOverflow(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown overflow strategy %q", ErrCustomUIDLayout, name)
+}
+
+// CustomUIDLayout CustomUID 的位布局
+// 从高到低依次为：时间戳、节点 ID、计数器、随机数，不足 Length*5 位时在最高位补 0
+type CustomUIDLayout struct {
+	// Epoch 时间戳基准点
+	Epoch time.Time
+	// TimeUnit 时间戳精度，必须整除 1 秒或为整数秒（例如 time.Second、time.Millisecond）
+	TimeUnit time.Duration
+	// TimestampBits 时间戳位数（1-64）
+	TimestampBits int
+	// NodeBits 节点 ID 位数（0-32）
+	NodeBits int
+	// CounterBits 计数器位数（0-32），为 0 时每个 ID 都重新生成随机数（只能使用默认溢出策略）
+	CounterBits int
+	// RandomBits 随机数位数（0-64）
+	RandomBits int
+	// NodeID 节点 ID，必须小于 2^NodeBits
+	NodeID uint64
+	// Length 编码后的字符数，0 表示按总位数自动计算（每字符 5 位）
+	Length int
+	// Overflow 计数器用尽时的处理策略，不影响编码
+	Overflow CustomUIDOverflow
+}
+
+// DefaultCustomUIDLayout 返回默认布局（GenerateCustomUID 使用的布局）
+func DefaultCustomUIDLayout() CustomUIDLayout {
+	return CustomUIDLayout{
+		Epoch:         time.Unix(customUIDEpoch, 0).UTC(),
+		TimeUnit:      time.Second,
+		TimestampBits: customUIDTimestampBits,
+		NodeBits:      customUIDNodeBits,
+		CounterBits:   customUIDCounterBits,
+		RandomBits:    customUIDRandomBits,
+	}
+}
+
+// TotalBits 返回布局的有效位数
+func (l CustomUIDLayout) TotalBits() int {
+	return l.TimestampBits + l.NodeBits + l.CounterBits + l.RandomBits
+}
+
+// EncodedLength 返回编码后的字符数
+func (l CustomUIDLayout) EncodedLength() int {
+	if l.Length > 0 {
+		return l.Length
+	}
+	return (l.TotalBits() + 4) / 5
+}
+
+func (l CustomUIDLayout) validate() error {
+	switch {
+	case l.TimestampBits < 1 || l.TimestampBits > 64:
+		return fmt.Errorf("%w: timestamp bits %d not in [1, 64]", ErrCustomUIDLayout, l.TimestampBits)
+	case l.NodeBits < 0 || l.NodeBits > 32:
+		return fmt.Errorf("%w: node bits %d not in [0, 32]", ErrCustomUIDLayout, l.NodeBits)
+	case l.CounterBits < 0 || l.CounterBits > 32:
+		return fmt.Errorf("%w: counter bits %d not in [0, 32]", ErrCustomUIDLayout, l.CounterBits)
+	case l.RandomBits < 0 || l.RandomBits > 64:
+		return fmt.Errorf("%w: random bits %d not in [0, 64]", ErrCustomUIDLayout, l.RandomBits)
+	case l.TotalBits() > customUIDMaxBits:
+		return fmt.Errorf("%w: %d bits exceed %d", ErrCustomUIDLayout, l.TotalBits(), customUIDMaxBits)
+	case l.NodeID >= 1<<l.NodeBits:
+		return fmt.Errorf("%w: node id %d does not fit in %d bits", ErrCustomUIDLayout, l.NodeID, l.NodeBits)
+	case l.TimeUnit <= 0 || (time.Second%l.TimeUnit != 0 && l.TimeUnit%time.Second != 0):
+		return fmt.Errorf("%w: time unit %s must divide or be a multiple of 1s", ErrCustomUIDLayout, l.TimeUnit)
+	case l.Length < 0 || l.Length > (customUIDMaxBits+4)/5:
+		return fmt.Errorf("%w: length %d not in [0, %d]", ErrCustomUIDLayout, l.Length, (customUIDMaxBits+4)/5)
+	case l.Length > 0 && l.Length*5 < l.TotalBits():
+		return fmt.Errorf("%w: %d bits do not fit in %d characters", ErrCustomUIDLayout, l.TotalBits(), l.Length)
+	case l.Overflow < 0 || int(l.Overflow) >= len(customUIDOverflowNames):
+		return fmt.Errorf("%w: unknown overflow strategy %d", ErrCustomUIDLayout, l.Overflow)
+	case l.Overflow != CustomUIDOverflowReroll && l.CounterBits == 0:
+		return fmt.Errorf("%w: overflow strategy %s requires counter bits", ErrCustomUIDLayout, l.Overflow)
+	}
+	return nil
+}
+
+// tickTime 将时间戳转换为时间
+func (l CustomUIDLayout) tickTime(tick uint64) time.Time {
+	var sec, nsec int64
+	if l.TimeUnit >= time.Second {
+		sec = int64(tick) * int64(l.TimeUnit/time.Second)
+	} else {
+		perSecond := uint64(time.Second / l.TimeUnit)
+		sec = int64(tick / perSecond)
+		nsec = int64(tick%perSecond) * int64(l.TimeUnit)
+	}
+	return time.Unix(l.Epoch.Unix()+sec, int64(l.Epoch.Nanosecond())+nsec)
+}
+
+// CustomUIDOption 配置 CustomUIDGenerator 的选项
+type CustomUIDOption func(*CustomUIDLayout)
+
+// WithCustomUIDLayout 使用完整的布局替换当前布局
+func WithCustomUIDLayout(layout CustomUIDLayout) CustomUIDOption {
+	return func(l *CustomUIDLayout) { *l = layout }
+}
+
+// WithCustomUIDEpoch 设置时间戳基准点
+func WithCustomUIDEpoch(epoch time.Time) CustomUIDOption {
+	return func(l *CustomUIDLayout) { l.Epoch = epoch }
+}
+
+// WithCustomUIDTimeUnit 设置时间戳精度（例如 time.Second、time.Millisecond）
+func WithCustomUIDTimeUnit(unit time.Duration) CustomUIDOption {
+	return func(l *CustomUIDLayout) { l.TimeUnit = unit }
+}
+
+// WithCustomUIDBits 设置时间戳、节点 ID、计数器和随机数的位数
+func WithCustomUIDBits(timestamp, node, counter, random int) CustomUIDOption {
+	return func(l *CustomUIDLayout) {
+		l.TimestampBits, l.NodeBits, l.CounterBits, l.RandomBits = timestamp, node, counter, random
+	}
+}
+
+// WithCustomUIDNodeID 设置节点 ID
+func WithCustomUIDNodeID(id uint64) CustomUIDOption {
+	return func(l *CustomUIDLayout) { l.NodeID = id }
+}
+
+// WithCustomUIDLength 设置编码后的字符数，0 表示自动计算
+func WithCustomUIDLength(n int) CustomUIDOption {
+	return func(l *CustomUIDLayout) { l.Length = n }
+}
+
+// WithCustomUIDOverflow 设置计数器用尽时的处理策略
+func WithCustomUIDOverflow(o CustomUIDOverflow) CustomUIDOption {
+	return func(l *CustomUIDLayout) { l.Overflow = o }
+}
+
+// ParseCustomUIDLayout 解析命令行使用的布局描述，返回对应的选项
+// 格式为逗号分隔的 key=value，未指定的字段保持默认布局的值，例如：
+//
+//	ts=42,unit=ms,node=0,counter=12,random=26
+//
+// 支持的 key：ts（或 timestamp）、node、counter、random、node-id、unit（s 或 ms）、
+// epoch（2006-01-02 或 RFC 3339）、length、overflow（reroll、wait、borrow 或 error）
+func ParseCustomUIDLayout(spec string) ([]CustomUIDOption, error) {
+	var opts []CustomUIDOption
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not key=value", ErrCustomUIDLayout, part)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "unit":
+			switch strings.ToLower(value) {
+			case "s", "sec", "second":
+				opts = append(opts, WithCustomUIDTimeUnit(time.Second))
+			case "ms", "millisecond":
+				opts = append(opts, WithCustomUIDTimeUnit(time.Millisecond))
+			default:
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("%w: unit %q", ErrCustomUIDLayout, value)
+				}
+				opts = append(opts, WithCustomUIDTimeUnit(d))
+			}
+			continue
+		case "epoch":
+			epoch, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				if epoch, err = time.Parse(time.DateOnly, value); err != nil {
+					return nil, fmt.Errorf("%w: epoch %q", ErrCustomUIDLayout, value)
+				}
+			}
+			opts = append(opts, WithCustomUIDEpoch(epoch))
+			continue
+		case "overflow":
+			o, err := ParseCustomUIDOverflow(value)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithCustomUIDOverflow(o))
+			continue
+		}
+
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s=%q is not a number", ErrCustomUIDLayout, key, value)
+		}
+		v := int(n)
+		switch key {
+		case "ts", "timestamp":
+			opts = append(opts, func(l *CustomUIDLayout) { l.TimestampBits = v })
+		case "node":
+			opts = append(opts, func(l *CustomUIDLayout) { l.NodeBits = v })
+		case "counter":
+			opts = append(opts, func(l *CustomUIDLayout) { l.CounterBits = v })
+		case "random":
+			opts = append(opts, func(l *CustomUIDLayout) { l.RandomBits = v })
+		case "node-id":
+			opts = append(opts, WithCustomUIDNodeID(n))
+		case "length":
+			opts = append(opts, WithCustomUIDLength(v))
+		default:
+			return nil, fmt.Errorf("%w: unknown key %q", ErrCustomUIDLayout, key)
+		}
+	}
+	return opts, nil
+}
+
+// CustomUIDGenerator 按指定布局生成 CustomUID，线程安全
+type CustomUIDGenerator struct {
+	layout CustomUIDLayout
+	length int
+	clock  Clock
+
+	mu         sync.Mutex
+	lastTick   uint64
+	started    bool
+	counter    uint64 // 计数器，在同一时间单位内递增
+	randomBase uint64 // 随机基数，每个时间单位更新一次
+}
+
+// NewCustomUIDGenerator 使用默认布局和选项构造生成器，布局不合法时返回 ErrCustomUIDLayout
+func NewCustomUIDGenerator(opts ...CustomUIDOption) (*CustomUIDGenerator, error) {
+	layout := DefaultCustomUIDLayout()
+	for _, opt := range opts {
+		opt(&layout)
+	}
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+	return &CustomUIDGenerator{
+		layout: layout,
+		length: layout.EncodedLength(),
+		clock:  SystemClock{},
+	}, nil
+}
+
+// defaultCustomUID GenerateCustomUID 使用的默认生成器
+var defaultCustomUID = func() *CustomUIDGenerator {
+	g, err := NewCustomUIDGenerator()
+	if err != nil {
+		panic(err)
+	}
+	return g
+}()
+
+// GenerateCustomUID 使用默认布局生成 16 字符的 CustomUID
 // 返回 16 字符的 UID，包含秒级时间戳和随机部分，支持时间排序
 func GenerateCustomUID() string {
-	// 获取当前时间（Unix 纪元以来的秒数）
-	now := time.Now().Unix()
-	
-	// 转换为相对于基准点（2025-10-01）的秒数
-	timestampSec := uint64(now - customUIDEpoch)
-	
-	// 确保时间戳不超过 50 位（2^50 - 1）
-	maxTimestamp := uint64(1<<customUIDTimestampBits - 1)
-	if timestampSec > maxTimestamp {
-		// 如果超出范围，使用最大值（理论上不会发生，因为 2^50 秒 ≈ 35,000,000 年）
-		timestampSec = maxTimestamp
-	}
-	
-	customUIDState.Lock()
-	
-	// 如果时间戳变化，重置计数器和随机基数
-	if now != customUIDState.lastSecond {
-		customUIDState.lastSecond = now
-		customUIDState.counter = 0
-		// 生成新的随机基数（14 位）
-		randomBytes := make([]byte, 2)
-		customUIDState.Unlock()
-		if _, err := rand.Read(randomBytes); err != nil {
-			panic(fmt.Sprintf("failed to generate random bytes: %v", err))
-		}
-		customUIDState.Lock()
-		customUIDState.randomBase = uint32(binary.BigEndian.Uint16(randomBytes))
-		customUIDState.randomBase = customUIDState.randomBase >> 2 // 取高 14 位
-		customUIDState.randomBase &= 0x3FFF                         // 确保只有 14 位
-	}
-	
-	// 递增计数器（16 位，最多 65536 个）
-	counter := customUIDState.counter
-	randomBase := customUIDState.randomBase
-	
-	customUIDState.counter++
-	if customUIDState.counter > customUIDMaxCounter {
-		// 如果计数器溢出，增加随机性并重置计数器
-		// 不等待，而是增加随机性
-		randomBytes := make([]byte, 2)
-		customUIDState.Unlock()
-		if _, err := rand.Read(randomBytes); err != nil {
-			panic(fmt.Sprintf("failed to generate random bytes: %v", err))
-		}
-		customUIDState.Lock()
-		customUIDState.randomBase = uint32(binary.BigEndian.Uint16(randomBytes))
-		customUIDState.randomBase = customUIDState.randomBase >> 2 // 取高 14 位
-		customUIDState.randomBase &= 0x3FFF                         // 确保只有 14 位
-		customUIDState.counter = 1 // 重置计数器
-		counter = 1
-		randomBase = customUIDState.randomBase
-	} else {
-		counter = customUIDState.counter
-		randomBase = customUIDState.randomBase
-	}
-	
-	customUIDState.Unlock()
-	
-	// 组合：计数器（16 位）+ 随机数（14 位）= 30 位
-	combinedRandom := (counter << customUIDRandomBits) | randomBase
-	
-	// 组合时间戳和随机数：时间戳（50 位）+ 组合随机数（30 位）= 80 位
-	// 使用 10 字节（80 位）存储，大端序
-	idBytes := make([]byte, 10)
-	
-	// 80 位的布局：
-	// [0:50] 时间戳（50 位）
-	// [50:80] 组合随机数（30 位：12 位计数器 + 18 位随机数）
-	//
-	// 使用大整数操作组合
-	var combinedHigh, combinedLow uint64
-	
-	// 高 64 位：时间戳（50 位在最高位）+ 组合随机数的高 14 位
-	// 时间戳左移 30 位，使 50 位在最高位
-	combinedHigh = timestampSec << (customUIDCounterBits + customUIDRandomBits) // 左移 30 位
-	
-	// 组合随机数的高 14 位放在 combinedHigh 的低 14 位
-	randomHigh14 := uint64(combinedRandom >> 16) // 高 14 位（30 - 16 = 14）
-	combinedHigh |= randomHigh14
-	
-	// 低 16 位：组合随机数的低 16 位
-	combinedLow = uint64(combinedRandom & 0xFFFF) // 低 16 位
-	
-	// 写入到字节数组
-	binary.BigEndian.PutUint64(idBytes[0:8], combinedHigh)
-	binary.BigEndian.PutUint16(idBytes[8:10], uint16(combinedLow))
-	
-	// Base32 编码为 16 字符
-	return encodeBase32_16(idBytes)
-}
-
-// encodeBase32_16 将 80 位（10 字节）数据编码为 Base32 字符串（16 字符）
-func encodeBase32_16(data []byte) string {
-	// 80 位 = 10 字节
-	// Base32 编码：每 5 位一个字符，80 位 = 16 字符（80/5 = 16）
-	
-	result := make([]byte, 16)
-	
-	// 将字节数组视为大端序的位流
-	var buffer uint64
-	var bitsInBuffer int
-	
-	pos := 0
-	for i := 0; i < 10; i++ {
-		buffer = (buffer << 8) | uint64(data[i])
-		bitsInBuffer += 8
-		
-		for bitsInBuffer >= 5 {
-			// 取高 5 位
-			index := (buffer >> (bitsInBuffer - 5)) & 0x1F
-			result[pos] = base32Chars[index]
-			pos++
-			bitsInBuffer -= 5
-			buffer &= (1<<bitsInBuffer - 1) // 清除已使用的位
-		}
-	}
-	
-	// 80 位正好是 5 的倍数，应该没有剩余位
-	if bitsInBuffer > 0 {
-		index := (buffer << (5 - bitsInBuffer)) & 0x1F
-		result[pos] = base32Chars[index]
-		pos++
-	}
-	
-	return string(result[:16])
+	return defaultCustomUID.Generate()
+}
+
+// Layout 返回生成器使用的布局
+func (g *CustomUIDGenerator) Layout() CustomUIDLayout { return g.layout }
+
+// Name 实现 Generator
+func (g *CustomUIDGenerator) Name() string { return "customuid" }
+
+// Meta 实现 Generator
+func (g *CustomUIDGenerator) Meta() Meta {
+	return Meta{
+		Length:      g.length,
+		Charset:     base32Chars,
+		Sortable:    true,
+		EntropyBits: float64(g.layout.RandomBits),
+		TimeUnit:    g.layout.TimeUnit,
+		NodeBits:    g.layout.NodeBits,
+		CounterBits: g.layout.CounterBits,
+	}
+}
+
+// Generate 实现 Generator
+// 只有 CustomUIDOverflowWait 和 CustomUIDOverflowError 策略可能失败，失败时 panic，
+// 需要处理错误时请使用 TryGenerate
+func (g *CustomUIDGenerator) Generate() string {
+	id, err := g.TryGenerate()
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate customuid: %v", err))
+	}
+	return id
+}
+
+// TryGenerate 实现 CheckedGenerator
+func (g *CustomUIDGenerator) TryGenerate() (string, error) {
+	tick, counter, randomBase, err := g.next()
+	if err != nil {
+		return "", err
+	}
+	return g.encode(tick, counter, randomBase), nil
+}
+
+// next 返回下一个 ID 的时间戳、计数器和随机数
+func (g *CustomUIDGenerator) next() (tick, counter, random uint64, err error) {
+	l := g.layout
+	tick = g.currentTick()
+
+	maxCounter := uint64(1)<<l.CounterBits - 1
+	var deadline time.Time
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for {
+		if g.started && l.Overflow != CustomUIDOverflowReroll && tick < g.lastTick {
+			// 时钟回拨或借用了时间戳：继续使用上一个时间单位，避免重新发出已生成的计数器值
+			tick = g.lastTick
+		}
+		// 如果时间戳变化，重置计数器和随机基数
+		if !g.started || tick != g.lastTick {
+			g.startTick(tick)
+		}
+
+		// 递增计数器
+		g.counter++
+		if g.counter <= maxCounter || l.Overflow != CustomUIDOverflowWait {
+			break
+		}
+		// 保持计数器为用尽状态，释放锁等待时钟进入下一个时间单位
+		g.counter = maxCounter
+		if deadline.IsZero() {
+			deadline = time.Now().Add(l.TimeUnit + 100*time.Millisecond)
+		}
+		last := g.lastTick
+		g.mu.Unlock()
+		tick, err = g.waitTick(last, deadline)
+		g.mu.Lock()
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if tick > g.lastTick {
+			g.startTick(tick)
+			g.counter = min(1, maxCounter)
+			return g.lastTick, g.counter, g.randomBase, nil
+		}
+		// 等待期间其他调用方已进入新的时间单位，在其中继续递增计数器
+	}
+	if g.counter > maxCounter {
+		switch l.Overflow {
+		case CustomUIDOverflowBorrow:
+			if l.TimestampBits < 64 && g.lastTick >= uint64(1)<<l.TimestampBits-1 {
+				g.counter = maxCounter
+				return 0, 0, 0, fmt.Errorf("%w: cannot borrow beyond the maximum timestamp", ErrCustomUIDOverflow)
+			}
+			g.startTick(g.lastTick + 1)
+			g.counter = min(1, maxCounter)
+		case CustomUIDOverflowError:
+			g.counter = maxCounter
+			return 0, 0, 0, ErrCustomUIDCounterExhausted
+		default:
+			// 如果计数器溢出，不等待，而是重新生成随机基数并重置计数器
+			g.randomBase = g.random()
+			g.counter = min(1, maxCounter)
+		}
+	}
+	return g.lastTick, g.counter, g.randomBase, nil
+}
+
+// waitTick 在不持有锁的情况下等待时钟越过 last，超过 deadline 时返回 ErrCustomUIDClockStalled
+// 系统时钟休眠到下一个时间单位的边界；注入的时钟可能按读取次数前进，只让出处理器后继续轮询
+func (g *CustomUIDGenerator) waitTick(last uint64, deadline time.Time) (uint64, error) {
+	_, system := g.clock.(SystemClock)
+	for {
+		if tick := g.currentTick(); tick > last {
+			return tick, nil
+		}
+		now := time.Now()
+		if now.After(deadline) {
+			return 0, ErrCustomUIDClockStalled
+		}
+		if system {
+			time.Sleep(min(g.layout.tickTime(last+1).Sub(now), deadline.Sub(now)))
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
+
+// startTick 进入新的时间单位，重置计数器和随机基数
+func (g *CustomUIDGenerator) startTick(tick uint64) {
+	g.started = true
+	g.lastTick = tick
+	g.counter = 0
+	g.randomBase = g.random()
+}
+
+// currentTick 返回当前时间相对于基准点的时间单位数，超出位数时取最大值
+func (g *CustomUIDGenerator) currentTick() uint64 {
+	elapsed := g.clock.Now().Sub(g.layout.Epoch)
+	if elapsed < 0 {
+		return 0
+	}
+	tick := uint64(elapsed / g.layout.TimeUnit)
+	if maxTick := uint64(1)<<g.layout.TimestampBits - 1; g.layout.TimestampBits < 64 && tick > maxTick {
+		// 理论上不会发生，超出范围时使用最大值
+		tick = maxTick
+	}
+	return tick
+}
+
+// random 生成 RandomBits 位的随机数
+func (g *CustomUIDGenerator) random() uint64 {
+	if g.layout.RandomBits == 0 {
+		return 0
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate random bytes: %v", err))
+	}
+	return binary.BigEndian.Uint64(b[:]) >> (64 - g.layout.RandomBits)
+}
+
+// encode 按布局组合各字段并编码为 Base32
+func (g *CustomUIDGenerator) encode(tick, counter, random uint64) string {
+	l := g.layout
+	var hi, lo uint64
+	lo = tick
+	hi, lo = shl128(hi, lo, l.NodeBits)
+	lo |= l.NodeID
+	hi, lo = shl128(hi, lo, l.CounterBits)
+	lo |= counter
+	hi, lo = shl128(hi, lo, l.RandomBits)
+	lo |= random
+	return encodeBase32(hi, lo, g.length)
+}
+
+// DecodeTime 实现 TimeDecoder
+func (g *CustomUIDGenerator) DecodeTime(id string) (time.Time, error) {
+	u, err := g.Parse(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return u.Time(), nil
+}
+
+// ExtractRandom 实现 RandomExtractor，返回 ID 中 RandomBits 位的随机数
+// 同一时间单位内的 ID 共用同一个随机基数，不是相互独立的随机数
+func (g *CustomUIDGenerator) ExtractRandom(id string) (uint64, error) {
+	u, err := g.Parse(id)
+	if err != nil {
+		return 0, err
+	}
+	return u.Random(), nil
+}
+
+// WithNodeID 实现 NodeConfigurable，布局必须包含节点 ID 字段
+func (g *CustomUIDGenerator) WithNodeID(id int64) (Generator, error) {
+	if id < 0 {
+		return nil, fmt.Errorf("%w: negative node id %d", ErrCustomUIDLayout, id)
+	}
+	if g.layout.NodeBits == 0 {
+		return nil, fmt.Errorf("%w: layout has no node bits", ErrCustomUIDLayout)
+	}
+	return g.derive(WithCustomUIDNodeID(uint64(id)))
+}
+
+// WithLayout 实现 LayoutConfigurable，spec 的格式见 ParseCustomUIDLayout
+func (g *CustomUIDGenerator) WithLayout(spec string) (Generator, error) {
+	opts, err := ParseCustomUIDLayout(spec)
+	if err != nil {
+		return nil, err
+	}
+	return g.derive(opts...)
+}
+
+// WithClock 实现 ClockConfigurable
+func (g *CustomUIDGenerator) WithClock(c Clock) (Generator, error) {
+	clone, err := g.derive()
+	if err != nil {
+		return nil, err
+	}
+	clone.clock = c
+	return clone, nil
+}
+
+// derive 基于当前布局和时钟构造新的生成器（状态不共享）
+func (g *CustomUIDGenerator) derive(opts ...CustomUIDOption) (*CustomUIDGenerator, error) {
+	clone, err := NewCustomUIDGenerator(append([]CustomUIDOption{WithCustomUIDLayout(g.layout)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	clone.clock = g.clock
+	return clone, nil
+}
+
+// CustomUID 解析后的 CustomUID
+type CustomUID struct {
+	layout    CustomUIDLayout
+	hi, lo    uint64
+	timestamp uint64 // 相对于基准点的时间单位数
+	node      uint64
+	counter   uint64
+	random    uint64
+}
+
+// ParseCustomUID 解析 GenerateCustomUID 生成的 16 字符 ID
+// 接受大小写字母，长度、字符集或取值不合法时返回对应的错误
+// GenerateCustomUID 的节点 ID 恒为 0，节点 ID 不为 0 时返回 ErrCustomUIDOverflow；
+// 其他布局或节点 ID 请使用 CustomUIDGenerator.Parse
+func ParseCustomUID(s string) (CustomUID, error) {
+	u, err := defaultCustomUID.Parse(s)
+	if err != nil {
+		return CustomUID{}, err
+	}
+	if u.node != 0 {
+		return CustomUID{}, fmt.Errorf("%w: reserved bits %#04x are not zero", ErrCustomUIDOverflow, u.node)
+	}
+	return u, nil
+}
+
+// DecodeCustomUIDTime 解析 GenerateCustomUID 生成的 ID 中嵌入的秒级时间戳
+func DecodeCustomUIDTime(id string) (time.Time, error) {
+	u, err := ParseCustomUID(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return u.Time(), nil
+}
+
+// Parse 按生成器的布局解析 ID
+func (g *CustomUIDGenerator) Parse(s string) (CustomUID, error) {
+	l := g.layout
+	if len(s) != g.length {
+		return CustomUID{}, fmt.Errorf("%w: got %d characters, want %d", ErrCustomUIDLength, len(s), g.length)
+	}
+	hi, lo, err := decodeBase32(s, l.TotalBits())
+	if err != nil {
+		return CustomUID{}, err
+	}
+
+	u := CustomUID{layout: l, hi: hi, lo: lo}
+	u.random = lo & mask64(l.RandomBits)
+	hi, lo = shr128(hi, lo, l.RandomBits)
+	u.counter = lo & mask64(l.CounterBits)
+	hi, lo = shr128(hi, lo, l.CounterBits)
+	u.node = lo & mask64(l.NodeBits)
+	_, lo = shr128(hi, lo, l.NodeBits)
+	u.timestamp = lo
+	return u, nil
+}
+
+// Time 返回 ID 中嵌入的生成时间（精度为布局的时间单位）
+func (u CustomUID) Time() time.Time { return u.layout.tickTime(u.timestamp) }
+
+// Node 返回 ID 中的节点 ID
+func (u CustomUID) Node() uint64 { return u.node }
+
+// Counter 返回 ID 中的计数器
+func (u CustomUID) Counter() uint64 { return u.counter }
+
+// Random 返回 ID 中的随机数
+func (u CustomUID) Random() uint64 { return u.random }
+
+// Bytes 返回 ID 的大端序原始表示（默认布局为 10 字节，80 位）
+func (u CustomUID) Bytes() []byte {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[0:8], u.hi)
+	binary.BigEndian.PutUint64(buf[8:16], u.lo)
+	return append([]byte(nil), buf[16-(u.layout.TotalBits()+7)/8:]...)
+}
+
+// String 返回 ID 的 Base32 编码
+func (u CustomUID) String() string {
+	return encodeBase32(u.hi, u.lo, u.layout.EncodedLength())
+}
+
+// base32Decode Crockford Base32 字符到 5 位值的映射，-1 表示非法字符（大小写均可）
+var base32Decode = func() [256]int8 {
+	var table [256]int8
+	for i := range table {
+		table[i] = -1
+	}
+	for i := 0; i < len(base32Chars); i++ {
+		c := base32Chars[i]
+		table[c] = int8(i)
+		if c >= 'A' && c <= 'Z' {
+			table[c+'a'-'A'] = int8(i)
+		}
+	}
+	return table
+}()
+
+// encodeBase32 将 128 位的值（hi 为高 64 位）编码为 length 个字符的 Base32 字符串
+// 每 5 位一个字符，从最低位开始编码，超出 length*5 的高位被丢弃
+func encodeBase32(hi, lo uint64, length int) string {
+	result := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		result[i] = base32Chars[lo&0x1F]
+		hi, lo = shr128(hi, lo, 5)
+	}
+	return string(result)
+}
+
+// decodeBase32 将 Crockford Base32 字符串解码为 128 位的值（hi 为高 64 位）
+// 编码时高位补齐的填充位必须为 0，即结果不能超过 bits 位
+func decodeBase32(s string, bits int) (hi, lo uint64, err error) {
+	for i := 0; i < len(s); i++ {
+		v := base32Decode[s[i]]
+		if v < 0 {
+			return 0, 0, fmt.Errorf("%w: %q at position %d", ErrCustomUIDCharset, s[i], i)
+		}
+		if hi>>59 != 0 {
+			return 0, 0, fmt.Errorf("%w: more than %d bits", ErrCustomUIDOverflow, customUIDMaxBits)
+		}
+		hi, lo = shl128(hi, lo, 5)
+		lo |= uint64(v)
+	}
+
+	// 检查超出 bits 位的高位是否为 0
+	if rh, rl := shr128(hi, lo, bits); rh != 0 || rl != 0 {
+		return 0, 0, fmt.Errorf("%w: %q does not fit in %d bits", ErrCustomUIDOverflow, s, bits)
+	}
+	return hi, lo, nil
+}
+
+// shl128 将 128 位的值左移 n 位（0 <= n <= 128）
+func shl128(hi, lo uint64, n int) (uint64, uint64) {
+	switch {
+	case n == 0:
+		return hi, lo
+	case n >= 128:
+		return 0, 0
+	case n >= 64:
+		return lo << (n - 64), 0
+	default:
+		return hi<<n | lo>>(64-n), lo << n
+	}
+}
+
+// shr128 将 128 位的值右移 n 位（0 <= n <= 128）
+func shr128(hi, lo uint64, n int) (uint64, uint64) {
+	switch {
+	case n == 0:
+		return hi, lo
+	case n >= 128:
+		return 0, 0
+	case n >= 64:
+		return 0, hi >> (n - 64)
+	default:
+		return hi >> n, lo>>n | hi<<(64-n)
+	}
+}
+
+// mask64 返回低 n 位全为 1 的掩码（0 <= n <= 64）
+func mask64(n int) uint64 {
+	if n >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<n - 1
 }
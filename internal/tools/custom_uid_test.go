@@ -0,0 +1,382 @@
+package tools
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCustomUID_RoundTrip 测试 encodeBase32 与 decodeBase32 互为逆运算
+func TestCustomUID_RoundTrip(t *testing.T) {
+	for _, bits := range []int{80, 64, 42, 128} {
+		length := (bits + 4) / 5
+		for i := 0; i < 1000; i++ {
+			var b [16]byte
+			if _, err := rand.Read(b[:]); err != nil {
+				t.Fatal(err)
+			}
+			hi, lo := shr128(binary.BigEndian.Uint64(b[0:8]), binary.BigEndian.Uint64(b[8:16]), 128-bits)
+			encoded := encodeBase32(hi, lo, length)
+			gotHi, gotLo, err := decodeBase32(encoded, bits)
+			if err != nil {
+				t.Fatalf("decodeBase32(%s, %d) error: %v", encoded, bits, err)
+			}
+			if gotHi != hi || gotLo != lo {
+				t.Fatalf("decodeBase32(%s, %d) = %x%016x, want %x%016x", encoded, bits, gotHi, gotLo, hi, lo)
+			}
+		}
+	}
+}
+
+// TestCustomUID_LegacyCompatible 测试默认布局与早期版本的编码逐字节兼容
+// 早期版本将时间戳左移 30 位写入高 64 位，再写入 30 位计数器 + 随机数的低 16 位
+func TestCustomUID_LegacyCompatible(t *testing.T) {
+	g, err := NewCustomUIDGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ tick, counter, random uint64 }{
+		{tick: 32891268, counter: 1, random: 0x1ABC},
+		{tick: 1<<34 - 1, counter: 65535, random: 1<<14 - 1},
+		{tick: 0, counter: 0, random: 0},
+	} {
+		combined := tc.counter<<customUIDRandomBits | tc.random
+		var legacy [10]byte
+		binary.BigEndian.PutUint64(legacy[0:8], tc.tick<<30|combined>>16)
+		binary.BigEndian.PutUint16(legacy[8:10], uint16(combined))
+		want := encodeBase32(binary.BigEndian.Uint64(legacy[0:8])>>48, binary.BigEndian.Uint64(legacy[2:10]), 16)
+
+		if got := g.encode(tc.tick, tc.counter, tc.random); got != want {
+			t.Errorf("encode(%+v) = %s, want legacy %s", tc, got, want)
+		}
+	}
+}
+
+// TestParseCustomUID 测试解析生成的 ID：时间、计数器、字节和字符串均与生成时一致
+func TestParseCustomUID(t *testing.T) {
+	before := time.Now().Truncate(time.Second)
+	var prevCounter uint64
+	for i := 0; i < 100; i++ {
+		id := GenerateCustomUID()
+		u, err := ParseCustomUID(id)
+		if err != nil {
+			t.Fatalf("ParseCustomUID(%s) error: %v", id, err)
+		}
+		if u.Time().Before(before) || u.Time().After(time.Now()) {
+			t.Errorf("ParseCustomUID(%s).Time() = %v, want between %v and now", id, u.Time(), before)
+		}
+		if u.Random() >= 1<<customUIDRandomBits {
+			t.Errorf("ParseCustomUID(%s).Random() = %d exceeds 14 bits", id, u.Random())
+		}
+		if u.Counter() == prevCounter {
+			t.Errorf("ParseCustomUID(%s).Counter() = %d repeats previous counter", id, u.Counter())
+		}
+		prevCounter = u.Counter()
+		if u.String() != id {
+			t.Errorf("ParseCustomUID(%s).String() = %s", id, u.String())
+		}
+		if len(u.Bytes()) != 10 {
+			t.Errorf("ParseCustomUID(%s).Bytes() has %d bytes, want 10", id, len(u.Bytes()))
+		}
+	}
+
+	// 小写字母同样合法
+	id := GenerateCustomUID()
+	if _, err := ParseCustomUID(strings.ToLower(id)); err != nil {
+		t.Errorf("ParseCustomUID(lowercase %s) error: %v", id, err)
+	}
+}
+
+// TestParseCustomUID_Invalid 测试非法输入返回对应的错误
+func TestParseCustomUID_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{name: "empty", input: "", want: ErrCustomUIDLength},
+		{name: "too short", input: "01YQGR8000000HA", want: ErrCustomUIDLength},
+		{name: "too long", input: "01YQGR8000000HASX", want: ErrCustomUIDLength},
+		{name: "excluded letter U", input: "01YQGR8000000HAU", want: ErrCustomUIDCharset},
+		{name: "punctuation", input: "01YQGR80000-0HAS", want: ErrCustomUIDCharset},
+		{name: "reserved bits set", input: "01YQGR8001000HAS", want: ErrCustomUIDOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCustomUID(tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("ParseCustomUID(%q) error = %v, want %v", tt.input, err, tt.want)
+			}
+		})
+	}
+
+	// 编码的填充位超出布局位数
+	if _, _, err := decodeBase32("ZZ", 8); !errors.Is(err, ErrCustomUIDOverflow) {
+		t.Errorf("decodeBase32(ZZ, 8) error = %v, want ErrCustomUIDOverflow", err)
+	}
+}
+
+// TestCustomUIDGenerator_Layouts 测试自定义布局的长度、字段和时间精度
+func TestCustomUIDGenerator_Layouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantLen int
+	}{
+		{name: "default", spec: "", wantLen: 16},
+		{name: "millisecond 64 bit", spec: "ts=42,unit=ms,node=0,counter=12,random=10", wantLen: 13},
+		{name: "node id", spec: "node=8,node-id=201,counter=8", wantLen: 13},
+		{name: "no counter", spec: "node=0,counter=0,random=30", wantLen: 13},
+		{name: "wide random", spec: "ts=40,unit=ms,node=0,counter=0,random=64", wantLen: 21},
+		{name: "explicit length", spec: "length=20", wantLen: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := defaultCustomUID.WithLayout(tt.spec)
+			if err != nil {
+				t.Fatalf("WithLayout(%q) error: %v", tt.spec, err)
+			}
+			cg := g.(*CustomUIDGenerator)
+			if got := cg.Meta().Length; got != tt.wantLen {
+				t.Errorf("Meta().Length = %d, want %d", got, tt.wantLen)
+			}
+
+			before := time.Now().Add(-cg.Layout().TimeUnit)
+			seen := make(map[string]bool)
+			for i := 0; i < 1000; i++ {
+				id := cg.Generate()
+				if len(id) != tt.wantLen {
+					t.Fatalf("Generate() = %s has length %d, want %d", id, len(id), tt.wantLen)
+				}
+				if seen[id] {
+					t.Fatalf("Generate() duplicate %s", id)
+				}
+				seen[id] = true
+
+				u, err := cg.Parse(id)
+				if err != nil {
+					t.Fatalf("Parse(%s) error: %v", id, err)
+				}
+				if u.Node() != cg.Layout().NodeID {
+					t.Errorf("Parse(%s).Node() = %d, want %d", id, u.Node(), cg.Layout().NodeID)
+				}
+				if u.Time().Before(before) || u.Time().After(time.Now()) {
+					t.Errorf("Parse(%s).Time() = %v, want between %v and now", id, u.Time(), before)
+				}
+				if u.String() != id {
+					t.Errorf("Parse(%s).String() = %s", id, u.String())
+				}
+			}
+		})
+	}
+}
+
+// TestCustomUIDGenerator_InvalidLayout 测试不合法的布局
+func TestCustomUIDGenerator_InvalidLayout(t *testing.T) {
+	specs := []string{
+		"ts=0",
+		"ts=65",
+		"counter=33",
+		"random=65",
+		"ts=64,node=32,counter=32,random=1",
+		"node=4,node-id=16",
+		"length=15",
+		"length=27",
+		"unit=7ms",
+		"bogus=1",
+		"ts",
+		"overflow=never",
+		"counter=0,overflow=wait",
+	}
+	for _, spec := range specs {
+		if _, err := defaultCustomUID.WithLayout(spec); !errors.Is(err, ErrCustomUIDLayout) {
+			t.Errorf("WithLayout(%q) error = %v, want ErrCustomUIDLayout", spec, err)
+		}
+	}
+	if _, err := NewCustomUIDGenerator(WithCustomUIDBits(40, 0, 10, 10)); err != nil {
+		t.Errorf("NewCustomUIDGenerator(40/0/10/10) error: %v", err)
+	}
+}
+
+// TestCustomUIDGenerator_Overflow 测试计数器用尽时各策略的行为
+// 布局只有 4 位计数器和 2 位随机数，冻结时钟下每个时间单位最多 15 个 ID
+func TestCustomUIDGenerator_Overflow(t *testing.T) {
+	const spec = "ts=42,unit=ms,node=0,counter=4,random=2"
+	frozen := ClockFunc(func() time.Time { return time.Now().Truncate(time.Hour) })
+	newGen := func(t *testing.T, overflow string, clock Clock) *CustomUIDGenerator {
+		t.Helper()
+		g, err := defaultCustomUID.WithLayout(spec + ",overflow=" + overflow)
+		if err != nil {
+			t.Fatalf("WithLayout error: %v", err)
+		}
+		g, err = g.(*CustomUIDGenerator).WithClock(clock)
+		if err != nil {
+			t.Fatalf("WithClock error: %v", err)
+		}
+		return g.(*CustomUIDGenerator)
+	}
+
+	t.Run("reroll", func(t *testing.T) {
+		g := newGen(t, "reroll", frozen)
+		seen := make(map[string]bool)
+		duplicates := 0
+		for i := 0; i < 200; i++ {
+			id := g.Generate()
+			if seen[id] {
+				duplicates++
+			}
+			seen[id] = true
+		}
+		// 同一时间单位内最多 15*4 个不同的 ID
+		if duplicates == 0 {
+			t.Errorf("reroll produced no duplicates for 200 IDs in one tick")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		g := newGen(t, "error", frozen)
+		for i := 0; i < 15; i++ {
+			if _, err := g.TryGenerate(); err != nil {
+				t.Fatalf("TryGenerate #%d error: %v", i, err)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			if id, err := g.TryGenerate(); !errors.Is(err, ErrCustomUIDCounterExhausted) {
+				t.Errorf("TryGenerate after exhaustion = %s, %v, want ErrCustomUIDCounterExhausted", id, err)
+			}
+		}
+	})
+
+	t.Run("wait frozen", func(t *testing.T) {
+		g := newGen(t, "wait", frozen)
+		for i := 0; i < 15; i++ {
+			if _, err := g.TryGenerate(); err != nil {
+				t.Fatalf("TryGenerate #%d error: %v", i, err)
+			}
+		}
+		if id, err := g.TryGenerate(); !errors.Is(err, ErrCustomUIDClockStalled) {
+			t.Errorf("TryGenerate after exhaustion = %s, %v, want ErrCustomUIDClockStalled", id, err)
+		}
+	})
+
+	// 每读取 50 次时钟前进 1ms，等待策略不应产生重复
+	var reads atomic.Int64
+	start := time.Now().Truncate(time.Hour)
+	stepping := ClockFunc(func() time.Time {
+		return start.Add(time.Duration(reads.Add(1)/50) * time.Millisecond)
+	})
+
+	for _, tc := range []struct {
+		overflow string
+		clock    Clock
+	}{
+		{"wait", stepping},
+		{"borrow", frozen},
+	} {
+		t.Run(tc.overflow+" unique", func(t *testing.T) {
+			g := newGen(t, tc.overflow, tc.clock)
+			ids := make([]string, 1000)
+			for i := range ids {
+				id, err := g.TryGenerate()
+				if err != nil {
+					t.Fatalf("TryGenerate #%d error: %v", i, err)
+				}
+				ids[i] = id
+			}
+			if !sort.StringsAreSorted(ids) {
+				t.Errorf("IDs are not sorted")
+			}
+			for i := 1; i < len(ids); i++ {
+				if ids[i] == ids[i-1] {
+					t.Fatalf("duplicate %s at %d", ids[i], i)
+				}
+			}
+		})
+	}
+}
+
+// TestCustomUIDGenerator_OverflowWait 测试等待策略：计数器用尽后在下一个时间单位从 1 重新计数，
+// 等待期间不持有锁；时钟停止时返回 ErrCustomUIDClockStalled，时钟恢复后继续生成
+func TestCustomUIDGenerator_OverflowWait(t *testing.T) {
+	// 每读取 50 次时钟前进 1ms；frozenAt 非零时时钟停在第 frozenAt 次读取的时间
+	var reads, frozenAt atomic.Int64
+	start := time.Now().Truncate(time.Hour)
+	clock := ClockFunc(func() time.Time {
+		n := reads.Add(1)
+		if f := frozenAt.Load(); f > 0 {
+			n = f
+		}
+		return start.Add(time.Duration(n/50) * time.Millisecond)
+	})
+	g, err := defaultCustomUID.WithLayout("ts=42,unit=ms,node=0,counter=4,random=2,overflow=wait")
+	if err != nil {
+		t.Fatalf("WithLayout error: %v", err)
+	}
+	g, err = g.(*CustomUIDGenerator).WithClock(clock)
+	if err != nil {
+		t.Fatalf("WithClock error: %v", err)
+	}
+	gen := g.(*CustomUIDGenerator)
+	generate := func(t *testing.T, want time.Time, counter uint64) {
+		t.Helper()
+		id, err := gen.TryGenerate()
+		if err != nil {
+			t.Fatalf("TryGenerate error: %v", err)
+		}
+		u, err := gen.Parse(id)
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", id, err)
+		}
+		if !u.Time().Equal(want) || u.Counter() != counter {
+			t.Errorf("TryGenerate = %s at %v with counter %d, want %v with counter %d", id, u.Time(), u.Counter(), want, counter)
+		}
+	}
+
+	// 前 15 个 ID 用尽第一个时间单位，第 16 个等待时钟前进后从计数器 1 开始
+	for i := uint64(1); i <= 15; i++ {
+		generate(t, start, i)
+	}
+	generate(t, start.Add(time.Millisecond), 1)
+
+	frozenAt.Store(reads.Load())
+	for i := uint64(2); i <= 15; i++ {
+		generate(t, start.Add(time.Millisecond), i)
+	}
+	done := make(chan error, 1)
+	polled := reads.Load()
+	go func() {
+		_, err := gen.TryGenerate()
+		done <- err
+	}()
+	for reads.Load() < polled+100 {
+		runtime.Gosched()
+	}
+	if !gen.mu.TryLock() {
+		t.Error("generator lock held while waiting for the clock")
+	} else {
+		gen.mu.Unlock()
+	}
+	if err := <-done; !errors.Is(err, ErrCustomUIDClockStalled) {
+		t.Errorf("TryGenerate with stopped clock = %v, want ErrCustomUIDClockStalled", err)
+	}
+	// 计数器保持用尽状态，再次调用仍然等待
+	if _, err := gen.TryGenerate(); !errors.Is(err, ErrCustomUIDClockStalled) {
+		t.Errorf("second TryGenerate with stopped clock = %v, want ErrCustomUIDClockStalled", err)
+	}
+
+	frozenAt.Store(0)
+	id, err := gen.TryGenerate()
+	if err != nil {
+		t.Fatalf("TryGenerate after the clock resumed error: %v", err)
+	}
+	if u, _ := gen.Parse(id); !u.Time().After(start.Add(time.Millisecond)) || u.Counter() != 1 {
+		t.Errorf("TryGenerate after the clock resumed = %v with counter %d, want a later tick with counter 1", u.Time(), u.Counter())
+	}
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Generator 描述一种 UID 生成方案
+// 所有方案都通过 Register 注册到全局注册表，由 uidstress、命令行工具和单元测试统一枚举
+type Generator interface {
+	// Name 返回方案的规范名称（小写，例如 "ulid"）
+	Name() string
+	// Generate 生成一个新的 ID
+	Generate() string
+	// Meta 返回方案的元数据
+	Meta() Meta
+}
+
+// TimeDecoder 由 ID 中嵌入了时间戳的方案实现，用于从 ID 中解析生成时间
+type TimeDecoder interface {
+	DecodeTime(id string) (time.Time, error)
+}
+
+// CheckedGenerator 由可能生成失败的方案实现（例如时钟回拨超出容忍范围）
+// TryGenerate 返回错误，而 Generate 在同样的情况下 panic
+type CheckedGenerator interface {
+	TryGenerate() (string, error)
+}
+
+// NodeConfigurable 由支持节点 ID 的方案实现，返回使用指定节点 ID 的新生成器
+type NodeConfigurable interface {
+	WithNodeID(id int64) (Generator, error)
+}
+
+// LayoutConfigurable 由支持自定义位布局的方案实现，spec 的格式由方案自行定义
+type LayoutConfigurable interface {
+	WithLayout(spec string) (Generator, error)
+}
+
+// RandomExtractor 由 ID 中包含随机部分的方案实现，用于取出随机部分（例如截断到少量位数验证生日界）
+// ExtractRandom 返回随机部分的低 64 位，随机部分不足 64 位时高位为 0，随机部分的位数见 Meta.EntropyBits
+// 没有注册提取函数的方案返回 ErrNoRandomPart
+type RandomExtractor interface {
+	ExtractRandom(id string) (uint64, error)
+}
+
+// ErrNoRandomPart 方案没有可提取的随机部分
+var ErrNoRandomPart = errors.New("scheme has no extractable random part")
+
+// Meta 方案的元数据，用于展示、校验和资源估算
+type Meta struct {
+	// Length ID 的字符长度，0 表示长度不固定
+	Length int
+	// Charset ID 使用的字符集
+	Charset string
+	// Sortable 生成的 ID 是否按时间字典序可排序
+	Sortable bool
+	// EntropyBits 每个 ID 中随机部分的位数
+	EntropyBits float64
+	// TimeUnit 嵌入时间戳的精度，0 表示不含时间戳；不同时间单位生成的 ID 互不相同
+	TimeUnit time.Duration
+	// NodeBits 节点 ID 的位数，不同节点 ID 生成的 ID 互不相同
+	NodeBits int
+	// CounterBits 同一时间单位内递增的计数器（序列号）位数，0 表示没有计数器
+	// 有计数器的方案中，同一个生成器在一个时间单位内生成的 ID 互不相同（计数器用尽前）
+	CounterBits int
+}
+
+// funcGenerator 将普通的生成函数包装为 Generator
+type funcGenerator struct {
+	name string
+	meta Meta
+	gen  func() string
+	// random 提取 ID 的随机部分，nil 表示不支持
+	random func(id string) (uint64, error)
+}
+
+// NewGenerator 使用生成函数和元数据构造 Generator
+func NewGenerator(name string, meta Meta, gen func() string) Generator {
+	return &funcGenerator{name: name, meta: meta, gen: gen}
+}
+
+func (g *funcGenerator) Name() string     { return g.name }
+func (g *funcGenerator) Generate() string { return g.gen() }
+func (g *funcGenerator) Meta() Meta       { return g.meta }
+
+// ExtractRandom 实现 RandomExtractor
+func (g *funcGenerator) ExtractRandom(id string) (uint64, error) {
+	if g.random == nil {
+		return 0, ErrNoRandomPart
+	}
+	return g.random(id)
+}
+
+// withRandom 返回设置了随机部分提取函数的 g 的副本，g 必须由本文件中的构造函数创建
+func withRandom(g Generator, random func(id string) (uint64, error)) Generator {
+	switch g := g.(type) {
+	case *funcGenerator:
+		clone := *g
+		clone.random = random
+		return &clone
+	case *timedGenerator:
+		clone := *g
+		clone.random = random
+		return &clone
+	case *clockedGenerator:
+		clone := *g
+		clone.random = random
+		return &clone
+	}
+	panic(fmt.Sprintf("withRandom: unsupported generator type %T", g))
+}
+
+// timedGenerator 在 funcGenerator 的基础上支持解析时间戳
+type timedGenerator struct {
+	funcGenerator
+	decode func(id string) (time.Time, error)
+}
+
+// NewTimedGenerator 构造同时实现 TimeDecoder 的 Generator
+func NewTimedGenerator(name string, meta Meta, gen func() string, decode func(id string) (time.Time, error)) Generator {
+	return &timedGenerator{
+		funcGenerator: funcGenerator{name: name, meta: meta, gen: gen},
+		decode:        decode,
+	}
+}
+
+func (g *timedGenerator) DecodeTime(id string) (time.Time, error) { return g.decode(id) }
+
+// clockedGenerator 基于时间的生成器，默认使用 gen（系统时钟），设置时钟后使用 genAt
+type clockedGenerator struct {
+	timedGenerator
+	genAt func(t time.Time) string
+	clock Clock
+}
+
+// NewClockedGenerator 构造同时实现 TimeDecoder 和 ClockConfigurable 的 Generator
+// gen 使用系统时钟生成 ID，genAt 使用指定时间生成 ID
+func NewClockedGenerator(name string, meta Meta, gen func() string, genAt func(t time.Time) string, decode func(id string) (time.Time, error)) Generator {
+	return &clockedGenerator{
+		timedGenerator: timedGenerator{
+			funcGenerator: funcGenerator{name: name, meta: meta, gen: gen},
+			decode:        decode,
+		},
+		genAt: genAt,
+	}
+}
+
+func (g *clockedGenerator) Generate() string {
+	if g.clock == nil {
+		return g.gen()
+	}
+	return g.genAt(g.clock.Now())
+}
+
+// WithClock 实现 ClockConfigurable
+func (g *clockedGenerator) WithClock(c Clock) (Generator, error) {
+	clone := *g
+	clone.clock = c
+	return &clone, nil
+}
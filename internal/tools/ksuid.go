@@ -1,9 +1,42 @@
 package tools
 
-import "github.com/segmentio/ksuid"
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
 
 // GenerateKSUID 生成 KSUID（K-Sortable Unique Identifier）
 // 返回 27 字符的 KSUID，包含时间戳和随机部分，支持时间排序
 func GenerateKSUID() string {
 	return ksuid.New().String()
 }
+
+// GenerateKSUIDAt 使用指定时间生成 KSUID
+func GenerateKSUIDAt(t time.Time) string {
+	k, err := ksuid.NewRandomWithTime(t)
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate ksuid: %v", err))
+	}
+	return k.String()
+}
+
+// DecodeKSUIDTime 解析 KSUID 中嵌入的秒级时间戳
+func DecodeKSUIDTime(id string) (time.Time, error) {
+	k, err := ksuid.Parse(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return k.Time(), nil
+}
+
+// ExtractKSUIDRandom 返回 KSUID 128 位随机部分的低 64 位
+func ExtractKSUIDRandom(id string) (uint64, error) {
+	k, err := ksuid.Parse(id)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(k.Payload()[8:]), nil
+}
@@ -1,6 +1,12 @@
 package tools
 
-import nanoid "github.com/matoous/go-nanoid/v2"
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	nanoid "github.com/matoous/go-nanoid/v2"
+)
 
 const (
 	defaultAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz-"
@@ -18,3 +24,29 @@ func GetNanoIdBy(length int) string {
 	id, _ := nanoid.Generate(defaultAlphabet, length)
 	return id
 }
+
+// NewNanoIdGenerator 返回使用默认字符集生成指定长度 NanoID 的 Generator，名称为 "nanoid<length>"
+// length 必须大于 0；注册表中的 nanoid16 即 NewNanoIdGenerator(16)
+func NewNanoIdGenerator(length int) Generator {
+	return withRandom(NewGenerator(fmt.Sprintf("nanoid%d", length), Meta{
+		Length:      length,
+		Charset:     defaultAlphabet,
+		Sortable:    false,
+		EntropyBits: float64(length) * math.Log2(float64(len(defaultAlphabet))),
+	}, func() string { return GetNanoIdBy(length) }), ExtractNanoIdRandom)
+}
+
+// ExtractNanoIdRandom 将默认字符集的 NanoID 视为 37 进制整数，返回其低 64 位
+// NanoID 的每个字符都是随机的，整个 ID 即随机部分
+func ExtractNanoIdRandom(id string) (uint64, error) {
+	var v uint64
+	for i := 0; i < len(id); i++ {
+		d := strings.IndexByte(defaultAlphabet, id[i])
+		if d < 0 {
+			return 0, fmt.Errorf("invalid nanoid %q: character %q not in alphabet", id, id[i])
+		}
+		// 乘法溢出时按 2^64 取模，结果恰为低 64 位
+		v = v*uint64(len(defaultAlphabet)) + uint64(d)
+	}
+	return v, nil
+}
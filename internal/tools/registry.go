@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// base62Chars KSUID 使用的 Base62 字符集
+const base62Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var registry struct {
+	sync.RWMutex
+	ordered []Generator          // 按注册顺序保存
+	byName  map[string]Generator // 规范名称和别名（小写）到方案的映射
+}
+
+func init() {
+	// 内置方案，新增方案只需在此追加一次 Register 调用
+	MustRegister(NewNanoIdGenerator(16), "nanoid")
+	MustRegister(withRandom(NewClockedGenerator("ulid", Meta{
+		Length:      26,
+		Charset:     base32Chars,
+		Sortable:    true,
+		EntropyBits: 80,
+		TimeUnit:    time.Millisecond,
+	}, GenerateULID, GenerateULIDAt, DecodeULIDTime), ExtractULIDRandom))
+	MustRegister(withRandom(NewClockedGenerator("ksuid", Meta{
+		Length:      27,
+		Charset:     base62Chars,
+		Sortable:    true,
+		EntropyBits: 128,
+		TimeUnit:    time.Second,
+	}, GenerateKSUID, GenerateKSUIDAt, DecodeKSUIDTime), ExtractKSUIDRandom))
+	MustRegister(defaultCustomUID, "custom")
+	MustRegister(withRandom(NewGenerator("uuidv4", Meta{
+		Length:      36,
+		Charset:     uuidChars,
+		Sortable:    false,
+		EntropyBits: 122,
+	}, GenerateUUIDv4), ExtractUUIDv4Random), "uuid4")
+	MustRegister(withRandom(NewClockedGenerator("uuidv7", Meta{
+		Length:      36,
+		Charset:     uuidChars,
+		Sortable:    true,
+		EntropyBits: 74,
+		TimeUnit:    time.Millisecond,
+	}, GenerateUUIDv7, GenerateUUIDv7At, DecodeUUIDv7Time), ExtractUUIDv7Random), "uuid7")
+	snowflake, err := NewSnowflake(SnowflakeConfig{})
+	if err != nil {
+		panic(err)
+	}
+	MustRegister(snowflake)
+}
+
+// Register 注册一个方案，aliases 为可选的别名
+// 名称不区分大小写，重复注册同一名称会返回错误
+func Register(g Generator, aliases ...string) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if registry.byName == nil {
+		registry.byName = make(map[string]Generator)
+	}
+	names := append([]string{g.Name()}, aliases...)
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if key == "" {
+			return fmt.Errorf("register scheme: empty name")
+		}
+		if _, ok := registry.byName[key]; ok {
+			return fmt.Errorf("register scheme: %q already registered", name)
+		}
+	}
+	for _, name := range names {
+		registry.byName[strings.ToLower(name)] = g
+	}
+	registry.ordered = append(registry.ordered, g)
+	return nil
+}
+
+// MustRegister 与 Register 相同，但注册失败时 panic
+func MustRegister(g Generator, aliases ...string) {
+	if err := Register(g, aliases...); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup 按名称或别名查找方案（不区分大小写）
+func Lookup(name string) (Generator, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	g, ok := registry.byName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheme %q", name)
+	}
+	return g, nil
+}
+
+// Generators 按注册顺序返回所有已注册的方案
+func Generators() []Generator {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	result := make([]Generator, len(registry.ordered))
+	copy(result, registry.ordered)
+	return result
+}
+
+// Names 按注册顺序返回所有已注册方案的规范名称
+func Names() []string {
+	gens := Generators()
+	names := make([]string, len(gens))
+	for i, g := range gens {
+		names[i] = g.Name()
+	}
+	return names
+}
@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Snowflake Twitter Snowflake 风格的 64 位 ID 生成器
+// 布局（从高到低）：1 位符号位（恒为 0）+ 时间戳 + 数据中心 ID + 工作节点 ID + 序列号
+// - 时间戳位数 = 63 - 数据中心位数 - 工作节点位数 - 序列号位数
+// - 同一时间单位内序列号递增，序列号用尽时等待下一个时间单位
+// - 时钟回拨不超过 MaxClockRollback 时等待时钟追上，否则返回 ErrClockRollback
+// - 等待时钟前进超过 MaxClockRollback（例如时钟被冻结）时返回 ErrClockStalled
+// 默认配置与 Twitter 相同：毫秒级时间戳（41 位）、5 位数据中心、5 位工作节点、12 位序列号
+
+// ErrClockRollback 时钟回拨超过容忍范围
+var ErrClockRollback = errors.New("snowflake: clock moved backwards")
+
+// ErrClockStalled 序列号用尽后时钟在容忍时间内没有前进
+var ErrClockStalled = errors.New("snowflake: clock did not advance")
+
+// ErrSnowflakeOverflow 时间戳超出可表示的范围
+var ErrSnowflakeOverflow = errors.New("snowflake: timestamp overflow")
+
+// SnowflakeConfig Snowflake 生成器配置，零值字段使用默认值
+type SnowflakeConfig struct {
+	// Epoch 时间戳基准点（默认 2025-10-01 00:00:00 UTC，与 CustomUID 相同）
+	Epoch time.Time
+	// TimeUnit 时间戳精度（默认 1ms）
+	TimeUnit time.Duration
+	// DatacenterBits 数据中心 ID 位数（默认 5）
+	DatacenterBits uint8
+	// WorkerBits 工作节点 ID 位数（默认 5）
+	WorkerBits uint8
+	// SequenceBits 序列号位数（默认 12）
+	SequenceBits uint8
+	// DatacenterID 数据中心 ID
+	DatacenterID int64
+	// WorkerID 工作节点 ID
+	WorkerID int64
+	// MaxClockRollback 可等待的最大时钟回拨，也是等待时钟前进的最长时间（默认 10ms）
+	MaxClockRollback time.Duration
+	// Clock 时间源（默认系统时钟）
+	Clock Clock
+}
+
+// SnowflakeParts Snowflake ID 解析后的各字段
+type SnowflakeParts struct {
+	Time         time.Time
+	DatacenterID int64
+	WorkerID     int64
+	Sequence     int64
+}
+
+// Snowflake 线程安全的 Snowflake ID 生成器
+type Snowflake struct {
+	cfg           SnowflakeConfig
+	timestampBits uint8
+
+	mu       sync.Mutex
+	lastTick int64
+	sequence int64
+}
+
+// NewSnowflake 按配置构造 Snowflake 生成器
+func NewSnowflake(cfg SnowflakeConfig) (*Snowflake, error) {
+	if cfg.Epoch.IsZero() {
+		cfg.Epoch = time.Unix(customUIDEpoch, 0).UTC()
+	}
+	if cfg.TimeUnit <= 0 {
+		cfg.TimeUnit = time.Millisecond
+	}
+	if cfg.DatacenterBits == 0 && cfg.WorkerBits == 0 && cfg.SequenceBits == 0 {
+		cfg.DatacenterBits, cfg.WorkerBits, cfg.SequenceBits = 5, 5, 12
+	}
+	if cfg.MaxClockRollback <= 0 {
+		cfg.MaxClockRollback = 10 * time.Millisecond
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock{}
+	}
+
+	used := int(cfg.DatacenterBits) + int(cfg.WorkerBits) + int(cfg.SequenceBits)
+	if used >= 63 {
+		return nil, fmt.Errorf("snowflake: node and sequence bits (%d) leave no room for the timestamp", used)
+	}
+	if cfg.DatacenterID < 0 || cfg.DatacenterID >= 1<<cfg.DatacenterBits {
+		return nil, fmt.Errorf("snowflake: datacenter id %d does not fit in %d bits", cfg.DatacenterID, cfg.DatacenterBits)
+	}
+	if cfg.WorkerID < 0 || cfg.WorkerID >= 1<<cfg.WorkerBits {
+		return nil, fmt.Errorf("snowflake: worker id %d does not fit in %d bits", cfg.WorkerID, cfg.WorkerBits)
+	}
+
+	return &Snowflake{
+		cfg:           cfg,
+		timestampBits: uint8(63 - used),
+		lastTick:      -1,
+	}, nil
+}
+
+// NextID 生成下一个 int64 ID
+func (s *Snowflake) NextID() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tick := s.currentTick()
+	if tick < s.lastTick {
+		// 时钟回拨：在容忍范围内等待时钟追上
+		rollback := time.Duration(s.lastTick-tick) * s.cfg.TimeUnit
+		if rollback > s.cfg.MaxClockRollback {
+			return 0, fmt.Errorf("%w by %s", ErrClockRollback, rollback)
+		}
+		for tick < s.lastTick {
+			time.Sleep(time.Duration(s.lastTick-tick) * s.cfg.TimeUnit)
+			tick = s.currentTick()
+		}
+	}
+
+	seqMask := int64(1)<<s.cfg.SequenceBits - 1
+	if tick == s.lastTick {
+		s.sequence = (s.sequence + 1) & seqMask
+		if s.sequence == 0 {
+			// 序列号用尽，等待下一个时间单位
+			deadline := time.Now().Add(s.cfg.MaxClockRollback)
+			for tick <= s.lastTick {
+				if time.Now().After(deadline) {
+					// 保持序列号为用尽状态，避免下次调用重复使用已发出的序列号
+					s.sequence = seqMask
+					return 0, ErrClockStalled
+				}
+				time.Sleep(s.cfg.TimeUnit / 10)
+				tick = s.currentTick()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	if tick >= 1<<s.timestampBits {
+		return 0, ErrSnowflakeOverflow
+	}
+	s.lastTick = tick
+
+	return tick<<(63-s.timestampBits) |
+		s.cfg.DatacenterID<<(s.cfg.WorkerBits+s.cfg.SequenceBits) |
+		s.cfg.WorkerID<<s.cfg.SequenceBits |
+		s.sequence, nil
+}
+
+func (s *Snowflake) currentTick() int64 {
+	return int64(s.cfg.Clock.Now().Sub(s.cfg.Epoch) / s.cfg.TimeUnit)
+}
+
+// Decompose 将 ID 拆分为时间、数据中心 ID、工作节点 ID 和序列号
+func (s *Snowflake) Decompose(id int64) SnowflakeParts {
+	seqShift := s.cfg.SequenceBits
+	workerShift := seqShift + s.cfg.WorkerBits
+	dcShift := workerShift + s.cfg.DatacenterBits
+	return SnowflakeParts{
+		Time:         s.cfg.Epoch.Add(time.Duration(id>>dcShift) * s.cfg.TimeUnit),
+		DatacenterID: id >> workerShift & (1<<s.cfg.DatacenterBits - 1),
+		WorkerID:     id >> seqShift & (1<<s.cfg.WorkerBits - 1),
+		Sequence:     id & (1<<s.cfg.SequenceBits - 1),
+	}
+}
+
+// Name 实现 Generator
+func (s *Snowflake) Name() string { return "snowflake" }
+
+// Generate 实现 Generator，返回十进制字符串形式的 ID
+// 时钟回拨超过容忍范围或时间戳溢出时 panic，需要处理错误时请使用 TryGenerate 或 NextID
+func (s *Snowflake) Generate() string {
+	id, err := s.TryGenerate()
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate snowflake id: %v", err))
+	}
+	return id
+}
+
+// TryGenerate 实现 CheckedGenerator
+func (s *Snowflake) TryGenerate() (string, error) {
+	id, err := s.NextID()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// Meta 实现 Generator
+func (s *Snowflake) Meta() Meta {
+	return Meta{
+		Length:      0,
+		Charset:     "0123456789",
+		Sortable:    true,
+		EntropyBits: 0,
+		TimeUnit:    s.cfg.TimeUnit,
+		NodeBits:    int(s.cfg.DatacenterBits + s.cfg.WorkerBits),
+		CounterBits: int(s.cfg.SequenceBits),
+	}
+}
+
+// DecodeTime 实现 TimeDecoder
+func (s *Snowflake) DecodeTime(id string) (time.Time, error) {
+	v, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if v < 0 {
+		return time.Time{}, fmt.Errorf("snowflake: negative id %d", v)
+	}
+	return s.Decompose(v).Time, nil
+}
+
+// WithNodeID 实现 NodeConfigurable
+// 节点 ID 的高位为数据中心 ID，低 WorkerBits 位为工作节点 ID
+func (s *Snowflake) WithNodeID(id int64) (Generator, error) {
+	cfg := s.cfg
+	cfg.DatacenterID = id >> cfg.WorkerBits
+	cfg.WorkerID = id & (1<<cfg.WorkerBits - 1)
+	if id < 0 || cfg.DatacenterID >= 1<<cfg.DatacenterBits {
+		return nil, fmt.Errorf("snowflake: node id %d does not fit in %d bits", id, cfg.DatacenterBits+cfg.WorkerBits)
+	}
+	return NewSnowflake(cfg)
+}
+
+// WithClock 实现 ClockConfigurable
+func (s *Snowflake) WithClock(c Clock) (Generator, error) {
+	cfg := s.cfg
+	cfg.Clock = c
+	return NewSnowflake(cfg)
+}
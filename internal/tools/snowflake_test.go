@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSnowflake_Decompose 测试生成的 ID 能拆分回配置的节点 ID 和时间
+func TestSnowflake_Decompose(t *testing.T) {
+	s, err := NewSnowflake(SnowflakeConfig{DatacenterID: 3, WorkerID: 17})
+	if err != nil {
+		t.Fatalf("NewSnowflake error: %v", err)
+	}
+
+	before := time.Now().Truncate(time.Millisecond)
+	id, err := s.NextID()
+	if err != nil {
+		t.Fatalf("NextID error: %v", err)
+	}
+	parts := s.Decompose(id)
+	if parts.DatacenterID != 3 || parts.WorkerID != 17 {
+		t.Errorf("Decompose node = %d/%d, want 3/17", parts.DatacenterID, parts.WorkerID)
+	}
+	if parts.Time.Before(before) || parts.Time.After(time.Now()) {
+		t.Errorf("Decompose time = %v, want between %v and now", parts.Time, before)
+	}
+
+	decoded, err := s.DecodeTime(strconv.FormatInt(id, 10))
+	if err != nil || !decoded.Equal(parts.Time) {
+		t.Errorf("DecodeTime = %v, %v, want %v", decoded, err, parts.Time)
+	}
+}
+
+// TestSnowflake_SequenceExhaustion 测试序列号用尽时等待下一个时间单位而不是重复
+func TestSnowflake_SequenceExhaustion(t *testing.T) {
+	s, err := NewSnowflake(SnowflakeConfig{SequenceBits: 2, WorkerBits: 1, DatacenterBits: 1})
+	if err != nil {
+		t.Fatalf("NewSnowflake error: %v", err)
+	}
+
+	seen := make(map[int64]bool)
+	var last int64
+	for i := 0; i < 50; i++ {
+		id, err := s.NextID()
+		if err != nil {
+			t.Fatalf("NextID error: %v", err)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %d at iteration %d", id, i)
+		}
+		if id <= last {
+			t.Fatalf("id %d not greater than previous %d", id, last)
+		}
+		seen[id] = true
+		last = id
+	}
+}
+
+// TestSnowflake_ClockRollback 测试时钟回拨：容忍范围内等待，超出范围返回错误
+func TestSnowflake_ClockRollback(t *testing.T) {
+	base := time.Now()
+	var offset atomic.Int64
+	s, err := NewSnowflake(SnowflakeConfig{
+		MaxClockRollback: 5 * time.Millisecond,
+		Clock:            ClockFunc(func() time.Time { return base.Add(time.Duration(offset.Load())) }),
+	})
+	if err != nil {
+		t.Fatalf("NewSnowflake error: %v", err)
+	}
+
+	first, err := s.NextID()
+	if err != nil {
+		t.Fatalf("NextID error: %v", err)
+	}
+
+	offset.Store(int64(-time.Second))
+	if _, err := s.NextID(); !errors.Is(err, ErrClockRollback) {
+		t.Fatalf("NextID after 1s rollback error = %v, want ErrClockRollback", err)
+	}
+
+	// 回拨 2ms：在容忍范围内，等待期间时钟恢复
+	offset.Store(int64(-2 * time.Millisecond))
+	go func() {
+		time.Sleep(time.Millisecond)
+		offset.Store(int64(time.Millisecond))
+	}()
+	second, err := s.NextID()
+	if err != nil {
+		t.Fatalf("NextID after 2ms rollback error: %v", err)
+	}
+	if second <= first {
+		t.Errorf("id after rollback %d not greater than %d", second, first)
+	}
+}
+
+// TestSnowflake_Config 测试非法配置
+func TestSnowflake_Config(t *testing.T) {
+	tests := []SnowflakeConfig{
+		{DatacenterBits: 20, WorkerBits: 20, SequenceBits: 23},
+		{DatacenterID: 32},
+		{WorkerID: -1},
+	}
+	for _, cfg := range tests {
+		if _, err := NewSnowflake(cfg); err == nil {
+			t.Errorf("NewSnowflake(%+v) expected error", cfg)
+		}
+	}
+
+	s, _ := NewSnowflake(SnowflakeConfig{})
+	g, err := s.WithNodeID(1<<5 | 9)
+	if err != nil {
+		t.Fatalf("WithNodeID error: %v", err)
+	}
+	id, _ := strconv.ParseInt(g.Generate(), 10, 64)
+	if parts := g.(*Snowflake).Decompose(id); parts.DatacenterID != 1 || parts.WorkerID != 9 {
+		t.Errorf("WithNodeID parts = %+v, want datacenter 1 worker 9", parts)
+	}
+	if _, err := s.WithNodeID(1 << 10); err == nil {
+		t.Errorf("WithNodeID(1024) expected error")
+	}
+}
+
+// TestSnowflake_FrozenClock 测试冻结时钟下序列号用尽后返回 ErrClockStalled 而不是一直等待
+func TestSnowflake_FrozenClock(t *testing.T) {
+	frozen := time.Now()
+	s, err := NewSnowflake(SnowflakeConfig{SequenceBits: 4, WorkerBits: 1, DatacenterBits: 1})
+	if err != nil {
+		t.Fatalf("NewSnowflake error: %v", err)
+	}
+	g, err := s.WithClock(ClockFunc(func() time.Time { return frozen }))
+	if err != nil {
+		t.Fatalf("WithClock error: %v", err)
+	}
+
+	cg := g.(CheckedGenerator)
+	for i := 0; i < 16; i++ {
+		if _, err := cg.TryGenerate(); err != nil {
+			t.Fatalf("TryGenerate #%d error: %v", i, err)
+		}
+	}
+	// 时钟不前进时每次调用都应失败，而不是重新使用已发出的序列号
+	for i := 0; i < 2; i++ {
+		if id, err := cg.TryGenerate(); !errors.Is(err, ErrClockStalled) {
+			t.Errorf("TryGenerate after sequence exhaustion = %s, %v, want ErrClockStalled", id, err)
+		}
+	}
+}
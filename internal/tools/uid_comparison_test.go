@@ -1,155 +1,286 @@
 package tools
 
 import (
+	"fmt"
+	"math"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
-// TestUID_LengthComparison 测试三种方案生成的 ID 长度
+// TestUID_LengthComparison 测试所有已注册方案生成的 ID 长度
 func TestUID_LengthComparison(t *testing.T) {
+	for _, g := range Generators() {
+		t.Run(g.Name(), func(t *testing.T) {
+			wantLen := g.Meta().Length
+			// 生成多个 ID 确保长度一致
+			for i := 0; i < 10; i++ {
+				id := g.Generate()
+				if wantLen == 0 {
+					// 长度不固定的方案只要求非空
+					if id == "" {
+						t.Errorf("%s generated empty ID", g.Name())
+					}
+					continue
+				}
+				if len(id) != wantLen {
+					t.Errorf("%s length = %v, want %v (ID: %s)", g.Name(), len(id), wantLen, id)
+				}
+			}
+		})
+	}
+}
+
+// TestUID_Charset 测试所有已注册方案生成的 ID 只包含声明的字符集
+func TestUID_Charset(t *testing.T) {
+	for _, g := range Generators() {
+		t.Run(g.Name(), func(t *testing.T) {
+			charset := g.Meta().Charset
+			for i := 0; i < 100; i++ {
+				id := g.Generate()
+				for _, c := range id {
+					if !strings.ContainsRune(charset, c) {
+						t.Fatalf("%s generated %q with character %q outside charset %q", g.Name(), id, c, charset)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestUID_Clock 测试基于时间的方案使用注入的时钟，并能从 ID 中解析出该时间
+func TestUID_Clock(t *testing.T) {
+	frozen := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return frozen })
+
+	for _, g := range Generators() {
+		cc, ok := g.(ClockConfigurable)
+		if !ok {
+			continue
+		}
+		t.Run(g.Name(), func(t *testing.T) {
+			clocked, err := cc.WithClock(clock)
+			if err != nil {
+				t.Fatalf("WithClock error: %v", err)
+			}
+			decoder, ok := clocked.(TimeDecoder)
+			if !ok {
+				t.Fatalf("%s implements ClockConfigurable but not TimeDecoder", g.Name())
+			}
+			ts, err := decoder.DecodeTime(clocked.Generate())
+			if err != nil {
+				t.Fatalf("DecodeTime error: %v", err)
+			}
+			if !ts.Equal(frozen) {
+				t.Errorf("DecodeTime = %v, want frozen clock %v", ts, frozen)
+			}
+		})
+	}
+}
+
+// TestRegistry_Lookup 测试按名称和别名查找方案
+func TestRegistry_Lookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "nanoid16", want: "nanoid16"},
+		{name: "nanoid", want: "nanoid16"},
+		{name: "ULID", want: "ulid"},
+		{name: "ksuid", want: "ksuid"},
+		{name: "custom", want: "customuid"},
+		{name: "uuid4", want: "uuidv4"},
+		{name: "UUIDv7", want: "uuidv7"},
+	}
+
+	for _, tt := range tests {
+		g, err := Lookup(tt.name)
+		if err != nil {
+			t.Fatalf("Lookup(%q) error: %v", tt.name, err)
+		}
+		if g.Name() != tt.want {
+			t.Errorf("Lookup(%q) = %s, want %s", tt.name, g.Name(), tt.want)
+		}
+	}
+
+	if _, err := Lookup("nope"); err == nil {
+		t.Errorf("Lookup(%q) expected error", "nope")
+	}
+	if err := Register(NewGenerator("ulid", Meta{}, GenerateULID)); err == nil {
+		t.Errorf("Register duplicate name expected error")
+	}
+}
+
+// TestNanoIdGenerator 测试指定长度的 NanoID 生成器的名称、长度和随机位数
+func TestNanoIdGenerator(t *testing.T) {
+	for _, length := range []int{1, 5, 16, 21} {
+		g := NewNanoIdGenerator(length)
+		if want := fmt.Sprintf("nanoid%d", length); g.Name() != want {
+			t.Errorf("NewNanoIdGenerator(%d).Name() = %s, want %s", length, g.Name(), want)
+		}
+		if id := g.Generate(); len(id) != length {
+			t.Errorf("NewNanoIdGenerator(%d) generated %q, want length %d", length, id, length)
+		}
+		if got, want := g.Meta().EntropyBits, float64(length)*math.Log2(37); math.Abs(got-want) > 1e-9 {
+			t.Errorf("NewNanoIdGenerator(%d) entropy = %g bits, want %g", length, got, want)
+		}
+	}
+
+	registered, err := Lookup("nanoid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered.Meta() != NewNanoIdGenerator(16).Meta() {
+		t.Errorf("registered nanoid16 meta %+v differs from NewNanoIdGenerator(16)", registered.Meta())
+	}
+}
+
+// TestUUID_VersionAndVariant 测试 UUID 的版本位、变体位和 UUIDv7 时间戳
+func TestUUID_VersionAndVariant(t *testing.T) {
 	tests := []struct {
 		name     string
 		generate func() string
-		wantLen  int
+		version  byte
 	}{
-		{
-			name:     "NanoID(16)",
-			generate: func() string { return GetNanoIdBy(16) },
-			wantLen:  16,
-		},
-		{
-			name:     "ULID",
-			generate: func() string { return GenerateULID() },
-			wantLen:  26,
-		},
-		{
-			name:     "KSUID",
-			generate: func() string { return GenerateKSUID() },
-			wantLen:  27,
-		},
-		{
-			name:     "CustomUID",
-			generate: func() string { return GenerateCustomUID() },
-			wantLen:  16,
-		},
+		{name: "UUIDv4", generate: GenerateUUIDv4, version: 4},
+		{name: "UUIDv7", generate: GenerateUUIDv7, version: 7},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// 生成多个 ID 确保长度一致
-			for i := 0; i < 10; i++ {
+			for i := 0; i < 100; i++ {
 				id := tt.generate()
-				if len(id) != tt.wantLen {
-					t.Errorf("%s length = %v, want %v (ID: %s)", tt.name, len(id), tt.wantLen, id)
+				u, err := parseUUID(id)
+				if err != nil {
+					t.Fatalf("parseUUID(%s) error: %v", id, err)
+				}
+				if got := u[6] >> 4; got != tt.version {
+					t.Fatalf("%s version = %d, want %d (ID: %s)", tt.name, got, tt.version, id)
+				}
+				if got := u[8] >> 6; got != 0b10 {
+					t.Fatalf("%s variant bits = %02b, want 10 (ID: %s)", tt.name, got, id)
 				}
 			}
 		})
 	}
+
+	now := time.Now().Truncate(time.Millisecond)
+	ts, err := DecodeUUIDv7Time(GenerateUUIDv7At(now))
+	if err != nil {
+		t.Fatalf("DecodeUUIDv7Time error: %v", err)
+	}
+	if !ts.Equal(now) {
+		t.Errorf("DecodeUUIDv7Time = %v, want %v", ts, now)
+	}
+	if _, err := DecodeUUIDv7Time(GenerateUUIDv4()); err == nil {
+		t.Errorf("DecodeUUIDv7Time(UUIDv4) expected error")
+	}
 }
 
-// BenchmarkUID_Comparison 对比测试，在同一基准下测试三种方案
-func BenchmarkUID_Comparison(b *testing.B) {
-	b.Run("NanoID_16", func(b *testing.B) {
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			GetNanoIdBy(16)
+// TestUID_ExtractRandom 测试各方案随机部分的提取
+func TestUID_ExtractRandom(t *testing.T) {
+	const maxUint64 = 1<<64 - 1
+	tests := []struct {
+		scheme string
+		id     string
+		want   uint64
+	}{
+		{scheme: "nanoid16", id: "0000000000000010", want: 37},
+		{scheme: "nanoid16", id: "000000000000000-", want: 36},
+		{scheme: "ulid", id: "00000000000000000000000001", want: 1},
+		{scheme: "ulid", id: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", want: maxUint64},
+		{scheme: "ksuid", id: "aWgEPTl1tmebfsQzFP4bxwgy80V", want: maxUint64},
+		{scheme: "uuidv4", id: "00000000-0000-4001-8000-000000000001", want: 1<<62 | 1},
+		{scheme: "uuidv4", id: "ffffffff-ffff-4fff-bfff-ffffffffffff", want: maxUint64},
+		{scheme: "uuidv7", id: "00000000-0000-7000-bf00-000000000000", want: 0x3F << 56},
+	}
+	for _, tt := range tests {
+		g, err := Lookup(tt.scheme)
+		if err != nil {
+			t.Fatal(err)
 		}
-	})
-
-	b.Run("ULID", func(b *testing.B) {
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			GenerateULID()
+		got, err := g.(RandomExtractor).ExtractRandom(tt.id)
+		if err != nil {
+			t.Fatalf("%s ExtractRandom(%s) error: %v", tt.scheme, tt.id, err)
 		}
-	})
-
-	b.Run("KSUID", func(b *testing.B) {
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			GenerateKSUID()
+		if got != tt.want {
+			t.Errorf("%s ExtractRandom(%s) = %#x, want %#x", tt.scheme, tt.id, got, tt.want)
 		}
-	})
+	}
 
-	b.Run("CustomUID", func(b *testing.B) {
-		b.ResetTimer()
-		for i := 0; i < b.N; i++ {
-			GenerateCustomUID()
-		}
-	})
-}
+	if _, err := ExtractUUIDv7Random(GenerateUUIDv4()); err == nil {
+		t.Errorf("ExtractUUIDv7Random(UUIDv4) expected error")
+	}
+	if _, err := ExtractNanoIdRandom("ABC"); err == nil {
+		t.Errorf("ExtractNanoIdRandom(%q) expected error", "ABC")
+	}
 
-// BenchmarkUID_Parallel 并发性能测试
-func BenchmarkUID_Parallel(b *testing.B) {
-	b.Run("NanoID_16", func(b *testing.B) {
-		b.RunParallel(func(pb *testing.PB) {
-			for pb.Next() {
-				GetNanoIdBy(16)
+	// 除 snowflake 外的方案都能从生成的 ID 中取出随机部分，且不超过 EntropyBits 位
+	for _, g := range Generators() {
+		ex, ok := g.(RandomExtractor)
+		if g.Name() == "snowflake" {
+			if ok {
+				t.Errorf("snowflake should not implement RandomExtractor")
 			}
-		})
-	})
-
-	b.Run("ULID", func(b *testing.B) {
-		b.RunParallel(func(pb *testing.PB) {
-			for pb.Next() {
-				GenerateULID()
+			continue
+		}
+		if !ok {
+			t.Fatalf("%s does not implement RandomExtractor", g.Name())
+		}
+		for i := 0; i < 100; i++ {
+			id := g.Generate()
+			v, err := ex.ExtractRandom(id)
+			if err != nil {
+				t.Fatalf("%s ExtractRandom(%s) error: %v", g.Name(), id, err)
 			}
-		})
-	})
+			if bits := g.Meta().EntropyBits; bits < 64 && v >= 1<<uint(bits) {
+				t.Fatalf("%s ExtractRandom(%s) = %#x exceeds %g bits", g.Name(), id, v, bits)
+			}
+		}
+	}
+}
 
-	b.Run("KSUID", func(b *testing.B) {
-		b.RunParallel(func(pb *testing.PB) {
-			for pb.Next() {
-				GenerateKSUID()
+// BenchmarkUID_Comparison 对比测试，在同一基准下测试所有已注册方案
+func BenchmarkUID_Comparison(b *testing.B) {
+	for _, g := range Generators() {
+		b.Run(g.Name(), func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.Generate()
 			}
 		})
-	})
+	}
+}
 
-	b.Run("CustomUID", func(b *testing.B) {
-		b.RunParallel(func(pb *testing.PB) {
-			for pb.Next() {
-				GenerateCustomUID()
-			}
+// BenchmarkUID_Parallel 并发性能测试
+func BenchmarkUID_Parallel(b *testing.B) {
+	for _, g := range Generators() {
+		b.Run(g.Name(), func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					g.Generate()
+				}
+			})
 		})
-	})
+	}
 }
 
-// TestUID_Uniqueness_Comparison 对比测试，生成 1,000,000 个 ID 进行唯一性对比
+// TestUID_Uniqueness_Comparison 对比测试，每个方案生成 1,000,000 个 ID 进行唯一性对比
 func TestUID_Uniqueness_Comparison(t *testing.T) {
 	const count = 1000000
 
-	tests := []struct {
-		name     string
-		generate func() string
-	}{
-		{
-			name:     "NanoID(16)",
-			generate: func() string { return GetNanoIdBy(16) },
-		},
-		{
-			name:     "ULID",
-			generate: func() string { return GenerateULID() },
-		},
-		{
-			name:     "KSUID",
-			generate: func() string { return GenerateKSUID() },
-		},
-		{
-			name:     "CustomUID",
-			generate: func() string { return GenerateCustomUID() },
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+	for _, g := range Generators() {
+		t.Run(g.Name(), func(t *testing.T) {
 			ids := make(map[string]bool, count)
 			duplicates := 0
 
 			for i := 0; i < count; i++ {
-				id := tt.generate()
+				id := g.Generate()
 				if ids[id] {
 					duplicates++
 					if duplicates == 1 {
-						t.Errorf("%s found duplicate ID at iteration %d: %s", tt.name, i, id)
+						t.Errorf("%s found duplicate ID at iteration %d: %s", g.Name(), i, id)
 					}
 				}
 				ids[id] = true
@@ -157,43 +288,21 @@ func TestUID_Uniqueness_Comparison(t *testing.T) {
 
 			uniqueCount := len(ids)
 			if uniqueCount != count {
-				t.Errorf("%s uniqueness: got %v unique IDs (expected %v), found %d duplicates", tt.name, uniqueCount, count, duplicates)
+				t.Errorf("%s uniqueness: got %v unique IDs (expected %v), found %d duplicates", g.Name(), uniqueCount, count, duplicates)
 			} else {
-				t.Logf("%s: Generated %d unique IDs, no duplicates found", tt.name, uniqueCount)
+				t.Logf("%s: Generated %d unique IDs, no duplicates found", g.Name(), uniqueCount)
 			}
 		})
 	}
 }
 
-// TestUID_ConcurrentSafety 测试三种方案在并发场景下的安全性
+// TestUID_ConcurrentSafety 测试所有已注册方案在并发场景下的安全性
 func TestUID_ConcurrentSafety(t *testing.T) {
 	const goroutines = 100
 	const idsPerGoroutine = 1000
 
-	tests := []struct {
-		name     string
-		generate func() string
-	}{
-		{
-			name:     "NanoID(16)",
-			generate: func() string { return GetNanoIdBy(16) },
-		},
-		{
-			name:     "ULID",
-			generate: func() string { return GenerateULID() },
-		},
-		{
-			name:     "KSUID",
-			generate: func() string { return GenerateKSUID() },
-		},
-		{
-			name:     "CustomUID",
-			generate: func() string { return GenerateCustomUID() },
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+	for _, g := range Generators() {
+		t.Run(g.Name(), func(t *testing.T) {
 			ids := make(chan string, goroutines*idsPerGoroutine)
 			var wg sync.WaitGroup
 
@@ -203,7 +312,7 @@ func TestUID_ConcurrentSafety(t *testing.T) {
 				go func() {
 					defer wg.Done()
 					for j := 0; j < idsPerGoroutine; j++ {
-						ids <- tt.generate()
+						ids <- g.Generate()
 					}
 				}()
 			}
@@ -221,7 +330,7 @@ func TestUID_ConcurrentSafety(t *testing.T) {
 				if uniqueIds[id] {
 					duplicates++
 					if duplicates == 1 {
-						t.Errorf("%s concurrent test: duplicate ID found: %s", tt.name, id)
+						t.Errorf("%s concurrent test: duplicate ID found: %s", g.Name(), id)
 					}
 				}
 				uniqueIds[id] = true
@@ -229,9 +338,9 @@ func TestUID_ConcurrentSafety(t *testing.T) {
 
 			expectedCount := goroutines * idsPerGoroutine
 			if len(uniqueIds) != expectedCount {
-				t.Errorf("%s concurrent uniqueness: got %v unique IDs (expected %v), found %d duplicates", tt.name, len(uniqueIds), expectedCount, duplicates)
+				t.Errorf("%s concurrent uniqueness: got %v unique IDs (expected %v), found %d duplicates", g.Name(), len(uniqueIds), expectedCount, duplicates)
 			} else {
-				t.Logf("%s: Concurrent test passed - Generated %d unique IDs from %d total, no duplicates", tt.name, len(uniqueIds), totalCount)
+				t.Logf("%s: Concurrent test passed - Generated %d unique IDs from %d total, no duplicates", g.Name(), len(uniqueIds), totalCount)
 			}
 		})
 	}
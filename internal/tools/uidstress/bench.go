@@ -0,0 +1,228 @@
+package uidstress
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// BenchConfig controls a micro-benchmark of ID generation. Unlike Run it
+// keeps no IDs: it measures the cost of Generate alone.
+type BenchConfig struct {
+	// Schemes lists the schemes to benchmark; empty or "all" means every
+	// registered scheme.
+	Schemes []string `json:"schemes"`
+	// Warmup is how long each scheme runs before measuring. It also
+	// calibrates the number of operations per repetition.
+	Warmup time.Duration `json:"warmup_ns"`
+	// Repetitions is the number of measured repetitions per scheme.
+	Repetitions int `json:"repetitions"`
+	// RepetitionTime is the target duration of one repetition.
+	RepetitionTime time.Duration `json:"repetition_time_ns"`
+	// Goroutines splits each repetition across this many goroutines; ns/op
+	// is then wall time per ID across all of them.
+	Goroutines int `json:"goroutines"`
+	// Alpha is the family-wise significance level of the pairwise
+	// comparisons; each pair is tested at Alpha divided by the number of
+	// pairs (Bonferroni).
+	Alpha  float64 `json:"alpha"`
+	NodeID int64   `json:"node_id,omitempty"`
+	Layout string  `json:"layout,omitempty"`
+}
+
+// BenchResult summarizes the repetitions of one scheme.
+type BenchResult struct {
+	Scheme string `json:"scheme"`
+	// Ops is the number of IDs generated in each repetition.
+	Ops         int64   `json:"ops"`
+	NsPerOp     Summary `json:"ns_per_op"`
+	BytesPerOp  Summary `json:"bytes_per_op"`
+	AllocsPerOp Summary `json:"allocs_per_op"`
+	nsSamples   []float64
+}
+
+// BenchComparison tells whether two schemes differ in ns/op.
+type BenchComparison struct {
+	// Faster and Slower name the schemes by mean ns/op.
+	Faster string `json:"faster"`
+	Slower string `json:"slower"`
+	// Ratio is the slower mean divided by the faster one.
+	Ratio float64 `json:"ratio"`
+	// P is the two-sided p-value of Welch's t-test.
+	P float64 `json:"p"`
+	// Significant reports whether P is below Alpha divided by the number
+	// of pairs compared.
+	Significant bool `json:"significant"`
+}
+
+// Bench benchmarks each scheme in turn and compares every pair of them.
+// Results are ordered fastest first.
+func Bench(ctx context.Context, cfg BenchConfig) ([]BenchResult, []BenchComparison, error) {
+	if len(cfg.Schemes) == 0 {
+		cfg.Schemes = []string{"all"}
+	}
+	if cfg.Warmup <= 0 {
+		cfg.Warmup = 500 * time.Millisecond
+	}
+	if cfg.Repetitions <= 0 {
+		cfg.Repetitions = 10
+	}
+	if cfg.Repetitions < 2 {
+		return nil, nil, fmt.Errorf("bench needs at least 2 repetitions, got %d", cfg.Repetitions)
+	}
+	if cfg.RepetitionTime <= 0 {
+		cfg.RepetitionTime = 500 * time.Millisecond
+	}
+	if cfg.Goroutines <= 0 {
+		cfg.Goroutines = 1
+	}
+	if cfg.Alpha <= 0 || cfg.Alpha >= 1 {
+		cfg.Alpha = 0.05
+	}
+	schemes, err := resolveSchemes(cfg.Schemes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]BenchResult, 0, len(schemes))
+	for _, scheme := range schemes {
+		gen, err := newGenerator(scheme, Config{NodeID: cfg.NodeID, Layout: cfg.Layout})
+		if err != nil {
+			return nil, nil, err
+		}
+		res, err := benchScheme(ctx, gen, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		res.Scheme = gen.Name()
+		results = append(results, res)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].NsPerOp.Mean < results[j].NsPerOp.Mean
+	})
+
+	var comparisons []BenchComparison
+	pairs := len(results) * (len(results) - 1) / 2
+	for i := range results {
+		for j := i + 1; j < len(results); j++ {
+			faster, slower := results[i], results[j]
+			c := BenchComparison{
+				Faster: faster.Scheme,
+				Slower: slower.Scheme,
+				P:      welchTTest(faster.nsSamples, slower.nsSamples),
+			}
+			if faster.NsPerOp.Mean > 0 {
+				c.Ratio = slower.NsPerOp.Mean / faster.NsPerOp.Mean
+			}
+			c.Significant = c.P < cfg.Alpha/float64(pairs)
+			comparisons = append(comparisons, c)
+		}
+	}
+	return results, comparisons, nil
+}
+
+// WriteBenchCSV writes one row per result with a header row: the ops per
+// repetition and the summary of ns/op, B/op and allocs/op.
+func WriteBenchCSV(w io.Writer, results []BenchResult) error {
+	cw := csv.NewWriter(w)
+	header := []string{"scheme", "ops"}
+	for _, metric := range []string{"ns_per_op", "bytes_per_op", "allocs_per_op"} {
+		for _, field := range []string{"n", "mean", "median", "stddev", "ci95_low", "ci95_high"} {
+			header = append(header, metric+"_"+field)
+		}
+	}
+	cw.Write(header)
+	for _, res := range results {
+		row := []string{res.Scheme, strconv.FormatInt(res.Ops, 10)}
+		for _, s := range []Summary{res.NsPerOp, res.BytesPerOp, res.AllocsPerOp} {
+			row = append(row, strconv.Itoa(s.N))
+			for _, v := range []float64{s.Mean, s.Median, s.StdDev, s.CILow, s.CIHigh} {
+				row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// benchScheme warms gen up, sizes a repetition from the warmup rate and
+// measures cfg.Repetitions repetitions of that size.
+func benchScheme(ctx context.Context, gen tools.Generator, cfg BenchConfig) (BenchResult, error) {
+	var warmupOps int64
+	start := time.Now()
+	for time.Since(start) < cfg.Warmup {
+		generateN(gen, 1024, cfg.Goroutines)
+		warmupOps += 1024
+	}
+	perOp := float64(time.Since(start)) / float64(warmupOps)
+	ops := max(int64(float64(cfg.RepetitionTime)/perOp), 1)
+
+	var ns, bytes, allocs []float64
+	for rep := 0; rep < cfg.Repetitions; rep++ {
+		if err := ctx.Err(); err != nil {
+			return BenchResult{}, err
+		}
+		// Start every repetition from a clean heap so one repetition's
+		// garbage is not collected on the next one's time.
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		t0 := time.Now()
+		generateN(gen, ops, cfg.Goroutines)
+		elapsed := time.Since(t0)
+		runtime.ReadMemStats(&after)
+
+		ns = append(ns, float64(elapsed.Nanoseconds())/float64(ops))
+		bytes = append(bytes, float64(after.TotalAlloc-before.TotalAlloc)/float64(ops))
+		allocs = append(allocs, float64(after.Mallocs-before.Mallocs)/float64(ops))
+	}
+	return BenchResult{
+		Ops:         ops,
+		NsPerOp:     summarize(ns),
+		BytesPerOp:  summarize(bytes),
+		AllocsPerOp: summarize(allocs),
+		nsSamples:   ns,
+	}, nil
+}
+
+// sink keeps the compiler from discarding generated IDs.
+var sink atomic.Int64
+
+// generateN generates n IDs split across goroutines.
+func generateN(gen tools.Generator, n int64, goroutines int) {
+	run := func(count int64) {
+		var id string
+		for i := int64(0); i < count; i++ {
+			id = gen.Generate()
+		}
+		sink.Add(int64(len(id)))
+	}
+	if goroutines <= 1 {
+		run(n)
+		return
+	}
+	var wg sync.WaitGroup
+	per, extra := n/int64(goroutines), n%int64(goroutines)
+	for g := 0; g < goroutines; g++ {
+		count := per
+		if int64(g) < extra {
+			count++
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(count)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,30 @@
+package uidstress
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestWriteBenchCSV checks the header and the column order of the summary
+// of each metric.
+func TestWriteBenchCSV(t *testing.T) {
+	var b strings.Builder
+	err := WriteBenchCSV(&b, []BenchResult{{
+		Scheme:      "ulid",
+		Ops:         1000,
+		NsPerOp:     Summary{N: 3, Mean: 50.5, Median: 50, StdDev: 1.5, CILow: 46.8, CIHigh: 54.2},
+		BytesPerOp:  Summary{N: 3, Mean: 48, Median: 48, CILow: 48, CIHigh: 48},
+		AllocsPerOp: Summary{N: 3, Mean: 2, Median: 2, CILow: 2, CIHigh: 2},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "scheme,ops," +
+		"ns_per_op_n,ns_per_op_mean,ns_per_op_median,ns_per_op_stddev,ns_per_op_ci95_low,ns_per_op_ci95_high," +
+		"bytes_per_op_n,bytes_per_op_mean,bytes_per_op_median,bytes_per_op_stddev,bytes_per_op_ci95_low,bytes_per_op_ci95_high," +
+		"allocs_per_op_n,allocs_per_op_mean,allocs_per_op_median,allocs_per_op_stddev,allocs_per_op_ci95_low,allocs_per_op_ci95_high\n" +
+		"ulid,1000,3,50.5,50,1.5,46.8,54.2,3,48,48,0,48,48,3,2,2,0,2,2\n"
+	if got := b.String(); got != want {
+		t.Errorf("WriteBenchCSV:\n%s\nwant:\n%s", got, want)
+	}
+}
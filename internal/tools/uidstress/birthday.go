@@ -0,0 +1,82 @@
+package uidstress
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"id-tester/internal/tools"
+)
+
+// truncator returns a function replacing each ID of gen by the low bits
+// bits of its random portion, as fixed-width hex so that lexicographic
+// order stays numeric order. The truncated values go through the normal
+// chunk, merge and dedupe pipeline, whose duplicate count can then be
+// checked against birthdayDuplicates.
+func truncator(gen tools.Generator, bits int) (func(id string) (string, error), error) {
+	ex, ok := gen.(tools.RandomExtractor)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", gen.Name(), tools.ErrNoRandomPart)
+	}
+	if bits < 1 || bits > 64 {
+		return nil, fmt.Errorf("truncate bits must be in [1, 64], got %d", bits)
+	}
+	meta := gen.Meta()
+	if float64(bits) > meta.EntropyBits {
+		return nil, fmt.Errorf("%s has only %g random bits, cannot truncate to %d", gen.Name(), meta.EntropyBits, bits)
+	}
+	// A counter run reuses one random part, so truncated IDs would collide
+	// far more often than the birthday bound predicts.
+	if meta.CounterBits > 0 {
+		return nil, fmt.Errorf("%s shares its random part across a %d-bit counter, cannot truncate it; use a layout without a counter", gen.Name(), meta.CounterBits)
+	}
+	mask := uint64(math.MaxUint64) >> (64 - bits)
+	width := (bits + 3) / 4
+	return func(id string) (string, error) {
+		v, err := ex.ExtractRandom(id)
+		if err != nil {
+			if errors.Is(err, tools.ErrNoRandomPart) {
+				return "", fmt.Errorf("%s: %w", gen.Name(), err)
+			}
+			return "", fmt.Errorf("truncate %s ID %q: %w", gen.Name(), id, err)
+		}
+		s := strconv.FormatUint(v&mask, 16)
+		if len(s) < width {
+			s = strings.Repeat("0", width-len(s)) + s
+		}
+		return s, nil
+	}, nil
+}
+
+// birthdayDuplicates returns the expected number of duplicates (n minus
+// the number of distinct values) among n values drawn uniformly from m,
+// and its standard deviation. For n much smaller than m this is the
+// familiar n²/2m with a Poisson spread.
+func birthdayDuplicates(n, m float64) (mean, stddev float64) {
+	if n <= 1 || m <= 0 {
+		return 0, 0
+	}
+	// a is the probability that a given value is never drawn. Working in
+	// logs keeps (1 - 1/m)^n accurate for m up to 2^64.
+	la := n * math.Log1p(-1/m)
+	a := math.Exp(la)
+	mean = n + m*math.Expm1(la)
+
+	// The number of distinct values has variance
+	// m·a + m(m-1)·b - m²·a², with b = (1 - 2/m)^n the probability that
+	// two given values are never drawn. Writing b = a²·e^d turns it into
+	// m·a·(m·a·expm1(d) - expm1(log a + d)), whose two terms are both
+	// about n/m, so nothing of order m cancels.
+	var d float64
+	if m > 1<<20 {
+		// log(1-2/m) - 2·log(1-1/m) by its series, which float64 would
+		// otherwise lose to cancellation.
+		d = -n * (1/(m*m) + 2/(m*m*m))
+	} else {
+		d = n * (math.Log1p(-2/m) - 2*math.Log1p(-1/m))
+	}
+	v := m * a * (m*a*math.Expm1(d) - math.Expm1(la+d))
+	return mean, math.Sqrt(max(v, 0))
+}
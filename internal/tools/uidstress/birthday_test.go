@@ -0,0 +1,124 @@
+package uidstress
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"id-tester/internal/tools"
+)
+
+// TestBirthdayDuplicates checks the expectation against exact small cases
+// and the n²/2m approximation.
+func TestBirthdayDuplicates(t *testing.T) {
+	tests := []struct {
+		n, m, mean, stddev, tol float64
+	}{
+		{2, 2, 0.5, 0.5, 1e-12},
+		{3, 3, 8.0 / 9, math.NaN(), 1e-12},
+		{1, 100, 0, 0, 0},
+		{1e5, 1 << 22, 1e10 / (1 << 23), math.Sqrt(1e10 / (1 << 23)), 0.02},
+		{1e6, 1 << 40, 1e12 / (1 << 41), math.Sqrt(1e12 / (1 << 41)), 1e-3},
+		{1e9, math.Ldexp(1, 64), 1e18 / math.Ldexp(1, 65), math.Sqrt(1e18 / math.Ldexp(1, 65)), 1e-3},
+	}
+	for _, tt := range tests {
+		mean, stddev := birthdayDuplicates(tt.n, tt.m)
+		if math.Abs(mean-tt.mean) > tt.tol*max(tt.mean, 1) {
+			t.Errorf("birthdayDuplicates(%g, %g) mean = %g, want %g", tt.n, tt.m, mean, tt.mean)
+		}
+		if !math.IsNaN(tt.stddev) && math.Abs(stddev-tt.stddev) > tt.tol*max(tt.stddev, 1) {
+			t.Errorf("birthdayDuplicates(%g, %g) stddev = %g, want %g", tt.n, tt.m, stddev, tt.stddev)
+		}
+	}
+}
+
+// TestTruncatedRunMatchesBirthday runs schemes whose random part is drawn
+// independently per ID, truncated to 22 bits over several chunks, and
+// checks the duplicates found by chunking and merging against the birthday
+// expectation.
+func TestTruncatedRunMatchesBirthday(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates 100k IDs per scheme")
+	}
+	results, err := Run(context.Background(), Config{
+		Schemes:      []string{"uuidv4", "nanoid16"},
+		Scale:        100_000,
+		ChunkSize:    25_000,
+		Workers:      2,
+		TempDir:      t.TempDir(),
+		TruncateBits: 22,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Generated != 100_000 || r.TotalDuplicates != r.Generated-r.Unique {
+			t.Errorf("%s: generated %d, unique %d, duplicates %d", r.Scheme, r.Generated, r.Unique, r.TotalDuplicates)
+		}
+		// About 1192 ± 34 expected; 5 standard deviations keep the test
+		// from flaking while still catching a biased extractor.
+		if z := r.DuplicatesZScore(); math.Abs(z) > 5 {
+			t.Errorf("%s: %d duplicates, expected %.1f ± %.1f (z = %+.2f)",
+				r.Scheme, r.TotalDuplicates, r.ExpectedDuplicates, r.ExpectedDuplicatesStdDev, z)
+		}
+	}
+}
+
+// TestTruncatorRejects checks the schemes and widths that cannot be
+// truncated.
+func TestTruncatorRejects(t *testing.T) {
+	tests := []struct {
+		scheme, layout string
+		bits           int
+		ok             bool
+	}{
+		{"uuidv4", "", 64, true},
+		{"nanoid16", "", 32, true},
+		{"uuidv4", "", 0, false},
+		{"uuidv4", "", 65, false},
+		{"customuid", "", 10, false},                   // random part shared across a counter
+		{"customuid", "counter=0,random=40", 32, true}, // random part drawn per ID
+		{"customuid", "counter=0,random=40", 41, false},
+		{"snowflake", "", 10, false},
+	}
+	for _, tt := range tests {
+		gen, err := newGenerator(tt.scheme, Config{Layout: tt.layout})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = truncator(gen, tt.bits)
+		if (err == nil) != tt.ok {
+			t.Errorf("truncator(%s %q, %d) error = %v, want ok %t", tt.scheme, tt.layout, tt.bits, err, tt.ok)
+		}
+	}
+
+	gen, err := newGenerator("snowflake", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := truncator(gen, 10); !errors.Is(err, tools.ErrNoRandomPart) {
+		t.Errorf("truncator(snowflake) error = %v, want %v", err, tools.ErrNoRandomPart)
+	}
+}
+
+// TestTruncatorWidth checks that truncated values are fixed-width hex of
+// the low bits of the random part.
+func TestTruncatorWidth(t *testing.T) {
+	gen, err := newGenerator("uuidv4", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trunc, err := truncator(gen, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := trunc("00000000-0000-4000-8000-0000000003ff")
+	if err != nil || v != "3ff" {
+		t.Errorf("truncate low 10 bits of ...3ff = %q, %v, want \"3ff\"", v, err)
+	}
+	v, err = trunc("00000000-0000-4000-8000-000000000401")
+	if err != nil || v != "001" {
+		t.Errorf("truncate low 10 bits of ...401 = %q, %v, want \"001\"", v, err)
+	}
+}
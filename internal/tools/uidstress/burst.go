@@ -0,0 +1,223 @@
+package uidstress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// BurstConfig controls a counter-overflow burst against customuid. A
+// simulated step clock advances one layout time unit every PerTick reads.
+// The generator reads the clock once per attempt, so each tick is offered
+// PerTick IDs regardless of how fast the machine is, except under the wait
+// strategy: its polling while the counter is exhausted reads the clock too,
+// so its ticks pass in fewer attempts and the burst spans more than Ticks
+// ticks (see BurstResult.Ticks). Each strategy runs against a fresh
+// generator and clock.
+type BurstConfig struct {
+	// Layout is the base customuid layout (see tools.ParseCustomUIDLayout);
+	// its overflow key is replaced by each strategy in turn.
+	Layout string
+	// Strategies lists the overflow strategies to compare (reroll, wait,
+	// borrow, error). Empty means all of them.
+	Strategies []string
+	// PerTick is the number of IDs requested per clock tick.
+	PerTick int64
+	// Ticks is the number of clock ticks the burst lasts.
+	Ticks int64
+	// Frozen stops the clock instead of stepping it, so the whole burst
+	// lands in a single tick.
+	Frozen     bool
+	Workers    int
+	MemGuardMB float64
+}
+
+// BurstResult summarizes one overflow strategy under burst.
+type BurstResult struct {
+	Strategy string
+	Duration time.Duration
+	// Requested is the number of generation attempts, PerTick*Ticks.
+	Requested int64
+	// Generated counts successful attempts; Errors counts failed ones.
+	Generated int64
+	Errors    int64
+	Unique    int64
+	// Duplicates is Generated - Unique: IDs issued more than once.
+	Duplicates int64
+	// CollisionRate is Duplicates / Generated.
+	CollisionRate float64
+	// Drift is how far the newest embedded timestamp runs ahead of the
+	// clock at the end of the burst (borrow strategy).
+	Drift time.Duration
+	// Ticks is the number of clock ticks the burst spanned: the configured
+	// Ticks for strategies reading the clock once per attempt, more for
+	// wait, and 1 with a frozen clock.
+	Ticks int64
+	// Err is set when the strategy could not finish, e.g. wait under a
+	// frozen clock.
+	Err error
+}
+
+// Burst runs the counter-overflow burst for each strategy and returns one
+// result per strategy. Strategy failures are reported in BurstResult.Err;
+// the returned error is reserved for invalid configuration and cancellation.
+func Burst(ctx context.Context, cfg BurstConfig) ([]BurstResult, error) {
+	if len(cfg.Strategies) == 0 {
+		cfg.Strategies = []string{"reroll", "wait", "borrow", "error"}
+	}
+	if cfg.PerTick <= 0 {
+		cfg.PerTick = 200_000
+	}
+	if cfg.Ticks <= 0 {
+		cfg.Ticks = 5
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	requested := cfg.PerTick * cfg.Ticks
+	if err := ensureMemory(Config{ApproxBytesPerID: defaultApproxBytesPerID, MemGuardMB: cfg.MemGuardMB}, requested); err != nil {
+		return nil, err
+	}
+
+	results := make([]BurstResult, 0, len(cfg.Strategies))
+	for _, strategy := range cfg.Strategies {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		overflow, err := tools.ParseCustomUIDOverflow(strings.TrimSpace(strategy))
+		if err != nil {
+			return nil, err
+		}
+		gen, clock, err := newBurstGenerator(cfg, overflow)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		res := runBurst(ctx, gen, clock, requested, cfg.Workers)
+		res.Strategy = overflow.String()
+		res.Duration = time.Since(start)
+		if errors.Is(res.Err, context.Canceled) {
+			return nil, res.Err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// newBurstGenerator builds a customuid generator with the given overflow
+// strategy and a step (or frozen) clock matching the layout's time unit.
+func newBurstGenerator(cfg BurstConfig, overflow tools.CustomUIDOverflow) (*tools.CustomUIDGenerator, tools.Clock, error) {
+	spec := "overflow=" + overflow.String()
+	if cfg.Layout != "" {
+		spec = cfg.Layout + "," + spec
+	}
+	opts, err := tools.ParseCustomUIDLayout(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := tools.NewCustomUIDGenerator(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clockSpec := fmt.Sprintf("step:every=%d,by=%s", cfg.PerTick, g.Layout().TimeUnit)
+	if cfg.Frozen {
+		clockSpec = "frozen"
+	}
+	clock, err := parseClock(clockSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+	clocked, err := g.WithClock(clock)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clocked.(*tools.CustomUIDGenerator), clock, nil
+}
+
+// runBurst makes requested generation attempts across workers goroutines
+// without retrying failures, then counts duplicates exactly by sorting.
+func runBurst(ctx context.Context, gen *tools.CustomUIDGenerator, clock tools.Clock, requested int64, workers int) BurstResult {
+	if int64(workers) > requested {
+		workers = int(requested)
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		ids      = make([]string, 0, requested)
+		errCount int64
+		firstErr error
+	)
+	per := requested / int64(workers)
+	extra := requested % int64(workers)
+	for w := 0; w < workers; w++ {
+		n := per
+		if int64(w) < extra {
+			n++
+		}
+		wg.Add(1)
+		go func(n int64) {
+			defer wg.Done()
+			local := make([]string, 0, n)
+			var failed int64
+			var err error
+			for i := int64(0); i < n; i++ {
+				if i%4096 == 0 && ctx.Err() != nil {
+					err = ctx.Err()
+					break
+				}
+				id, genErr := gen.TryGenerate()
+				if genErr != nil {
+					failed++
+					if errors.Is(genErr, tools.ErrCustomUIDClockStalled) {
+						// The clock will not move again; every further
+						// attempt would block for a full time unit.
+						err = genErr
+						break
+					}
+					continue
+				}
+				local = append(local, id)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			ids = append(ids, local...)
+			errCount += failed
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	res := BurstResult{
+		Requested: requested,
+		Generated: int64(len(ids)),
+		Errors:    errCount,
+		Err:       firstErr,
+		Ticks:     1,
+	}
+	if sc, ok := clock.(*stepClock); ok {
+		res.Ticks = sc.tick() + 1
+	}
+	if len(ids) == 0 {
+		return res
+	}
+	sort.Strings(ids)
+	if newest, err := gen.DecodeTime(ids[len(ids)-1]); err == nil {
+		res.Drift = max(0, newest.Sub(clock.Now().Truncate(gen.Layout().TimeUnit)))
+	}
+	unique, _ := dedupeSorted(ids)
+	res.Unique = int64(len(unique))
+	res.Duplicates = res.Generated - res.Unique
+	res.CollisionRate = float64(res.Duplicates) / float64(res.Generated)
+	return res
+}
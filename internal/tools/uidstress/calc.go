@@ -0,0 +1,299 @@
+package uidstress
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CalcConfig describes a deployment whose collision odds Calc computes from
+// each scheme's bit layout (tools.Meta), without generating any IDs.
+type CalcConfig struct {
+	// Schemes lists the schemes to compute; empty or "all" means every
+	// registered scheme.
+	Schemes []string `json:"schemes"`
+	// Rate is the number of IDs each node generates per second.
+	Rate float64 `json:"rate"`
+	// Nodes is the number of generators running at the same time.
+	Nodes int `json:"nodes"`
+	// Duration is the period the collision probability is computed for.
+	Duration time.Duration `json:"duration_ns"`
+	// Target is the collision probability CalcResult.TimeToTarget is
+	// computed for (default 0.01).
+	Target float64 `json:"target"`
+	// DistinctNodeIDs gives every node its own node ID as far as the
+	// scheme's node bits allow. Without it every node runs with the same
+	// node ID, as when the node ID is left at its default of 0.
+	DistinctNodeIDs bool   `json:"distinct_node_ids"`
+	Layout          string `json:"layout,omitempty"`
+}
+
+// CalcResult is the collision estimate of one scheme.
+type CalcResult struct {
+	Scheme string `json:"scheme"`
+	// Bucket is the scheme's time unit: IDs from different buckets never
+	// collide. Zero means the scheme embeds no time and every ID of the
+	// whole duration may collide with every other.
+	Bucket      time.Duration `json:"bucket_ns"`
+	RandomBits  float64       `json:"random_bits"`
+	NodeBits    int           `json:"node_bits"`
+	CounterBits int           `json:"counter_bits"`
+	// IDsPerBucket is the number of IDs each node generates per bucket
+	// (per the whole duration when Bucket is zero).
+	IDsPerBucket float64 `json:"ids_per_bucket"`
+	// Impossible is set when the layout rules collisions out: counter
+	// based schemes whose nodes all have distinct node IDs.
+	Impossible bool `json:"impossible,omitempty"`
+	// Probability is the chance of at least one collision within the
+	// duration, and ExpectedDuplicates the mean number of duplicate IDs.
+	Probability        float64 `json:"probability"`
+	ExpectedDuplicates float64 `json:"expected_duplicates"`
+	// UnbucketedProbability is what Probability would be if IDs of
+	// different buckets could collide too, for random schemes with a
+	// time bucket: the gap between the two is what the bucket buys.
+	UnbucketedProbability float64 `json:"unbucketed_probability,omitempty"`
+	// MeanTimeToCollision is the expected time until the first collision
+	// and TimeToTarget the time until the collision probability reaches
+	// CalcConfig.Target, both in seconds; zero when Impossible.
+	MeanTimeToCollision float64 `json:"mean_time_to_collision_seconds"`
+	TimeToTarget        float64 `json:"time_to_target_seconds"`
+	// CounterOverflow is set when a node generates more IDs per bucket
+	// than the counter holds. Snowflake then waits for the next bucket,
+	// so the rate is not reached; CustomUID re-rolls its random base by
+	// default, which this estimate does not model.
+	CounterOverflow bool `json:"counter_overflow,omitempty"`
+}
+
+// Calc estimates, for each scheme, the collision probability over
+// cfg.Duration and the time until the first collision when cfg.Nodes nodes
+// each generate cfg.Rate IDs per second.
+//
+// IDs can only collide within the same time bucket and, for schemes with
+// node bits, the same node ID. Random schemes are treated as birthday
+// problems over 2^RandomBits values per bucket, with IDs arriving as a
+// Poisson process; the monotonic increments ULID uses within one
+// millisecond are approximated as independent draws. Counter based schemes
+// (snowflake, customuid) never collide within one node; two nodes sharing
+// a node ID collide in a bucket when both generate in it and their random
+// bases (if any) are equal, and then on every counter value both reach. A
+// node generating fewer than one ID per bucket is active in that fraction
+// of buckets, independently of the others.
+func Calc(cfg CalcConfig) ([]CalcResult, error) {
+	if len(cfg.Schemes) == 0 {
+		cfg.Schemes = []string{"all"}
+	}
+	if cfg.Rate <= 0 {
+		return nil, fmt.Errorf("rate must be > 0, got %g", cfg.Rate)
+	}
+	if cfg.Nodes <= 0 {
+		cfg.Nodes = 1
+	}
+	if cfg.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be > 0, got %s", cfg.Duration)
+	}
+	if cfg.Target <= 0 || cfg.Target >= 1 {
+		cfg.Target = 0.01
+	}
+	schemes, err := resolveSchemes(cfg.Schemes)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CalcResult, 0, len(schemes))
+	for _, scheme := range schemes {
+		gen, err := newGenerator(scheme, Config{Layout: cfg.Layout})
+		if err != nil {
+			return nil, err
+		}
+		meta := gen.Meta()
+		res := calcScheme(cfg, meta.TimeUnit, meta.EntropyBits, meta.NodeBits, meta.CounterBits)
+		res.Scheme = gen.Name()
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// nodeGroup is count node IDs each shared by size nodes.
+type nodeGroup struct {
+	size, count float64
+}
+
+// nodeGroups spreads nodes over the 2^nodeBits node IDs as evenly as
+// possible when distinct is set, or puts them all on one node ID.
+func nodeGroups(nodes, nodeBits int, distinct bool) []nodeGroup {
+	n := float64(nodes)
+	ids := math.Ldexp(1, nodeBits)
+	if !distinct || nodeBits == 0 {
+		return []nodeGroup{{size: n, count: 1}}
+	}
+	if n <= ids {
+		return []nodeGroup{{size: 1, count: n}}
+	}
+	q := math.Floor(n / ids)
+	rem := n - q*ids
+	return []nodeGroup{{size: q + 1, count: rem}, {size: q, count: ids - rem}}
+}
+
+// calcScheme computes the estimate for one bit layout.
+func calcScheme(cfg CalcConfig, bucket time.Duration, randomBits float64, nodeBits, counterBits int) CalcResult {
+	res := CalcResult{
+		Bucket:      bucket,
+		RandomBits:  randomBits,
+		NodeBits:    nodeBits,
+		CounterBits: counterBits,
+	}
+	seconds := cfg.Duration.Seconds()
+	span := bucket.Seconds() // length of one bucket
+	if bucket <= 0 {
+		span = seconds
+	}
+	buckets := seconds / span
+	k := cfg.Rate * span
+	m := math.Exp2(randomBits)
+	groups := nodeGroups(cfg.Nodes, nodeBits, cfg.DistinctNodeIDs)
+	res.IDsPerBucket = k
+
+	// logNo is the log-probability that a single bucket has no collision.
+	var logNo float64
+	if counterBits > 0 {
+		res.CounterOverflow = k > math.Exp2(float64(counterBits))-1
+		active := min(k, 1)
+		for _, g := range groups {
+			if g.count == 0 || g.size < 2 {
+				continue
+			}
+			lno, dup := sharedNodeCollision(g.size, active, m)
+			logNo += g.count * lno
+			res.ExpectedDuplicates += g.count * dup * max(k, 1) * buckets
+		}
+		if logNo == 0 {
+			res.Impossible = true
+			return res
+		}
+	} else {
+		for _, g := range groups {
+			lno, dup := poissonCollision(g.size*k, m)
+			logNo += g.count * lno
+			res.ExpectedDuplicates += g.count * dup * buckets
+		}
+	}
+	res.Probability = -math.Expm1(buckets * logNo)
+
+	target := -math.Log1p(-cfg.Target)
+	if bucket > 0 || counterBits > 0 {
+		// Buckets are independent trials: the first collision comes after
+		// a geometric number of them.
+		pBucket := -math.Expm1(logNo)
+		res.MeanTimeToCollision = span / pBucket
+		res.TimeToTarget = span * target / -logNo
+		if counterBits == 0 {
+			var unbucketed float64
+			for _, g := range groups {
+				lno, _ := poissonCollision(g.size*cfg.Rate*seconds, m)
+				unbucketed += g.count * lno
+			}
+			res.UnbucketedProbability = -math.Expm1(unbucketed)
+		}
+		return res
+	}
+
+	// Without buckets every group is one pool growing at size·Rate IDs
+	// per second, and P(no collision by t) ≈ exp(-Σ count·(size·Rate·t)²/2M).
+	var sq float64
+	for _, g := range groups {
+		sq += g.count * g.size * g.size
+	}
+	res.MeanTimeToCollision = math.Sqrt(math.Pi*m/(2*sq)) / cfg.Rate
+	res.TimeToTarget = math.Sqrt(2*m*target/sq) / cfg.Rate
+	return res
+}
+
+// poissonCollision returns the log-probability that a Poisson number of
+// values with mean s, drawn uniformly from m, are all distinct, and the
+// expected number of duplicates among them. Every one of the m values is
+// then drawn a Poisson(s/m) number of times, independently.
+func poissonCollision(s, m float64) (logNo, duplicates float64) {
+	if s <= 0 {
+		return 0, 0
+	}
+	l := s / m
+	if l < 1e-4 {
+		// Series of log1p(l) - l and l + expm1(-l), which cancel.
+		return m * (-l*l/2 + l*l*l/3), m * (l*l/2 - l*l*l/6)
+	}
+	return m * (math.Log1p(l) - l), m * (l + math.Expm1(-l))
+}
+
+// sharedNodeCollision returns the log-probability that no two of size
+// nodes sharing a node ID collide in one bucket, when each is active in it
+// with probability active and active nodes draw a random base from m,
+// along with the expected number of colliding active nodes.
+func sharedNodeCollision(size, active, m float64) (logNo, duplicates float64) {
+	if active >= 1 {
+		dup, _ := birthdayDuplicates(size, m)
+		return logNoCollision(size, m), dup
+	}
+	// Sum over the binomial number j of active nodes.
+	var pNo float64
+	lgN, _ := math.Lgamma(size + 1)
+	for j := 0.0; j <= size; j++ {
+		lgJ, _ := math.Lgamma(j + 1)
+		lgNJ, _ := math.Lgamma(size - j + 1)
+		pj := math.Exp(lgN - lgJ - lgNJ + j*math.Log(active) + (size-j)*math.Log1p(-active))
+		pNo += pj * math.Exp(logNoCollision(j, m))
+		dup, _ := birthdayDuplicates(j, m)
+		duplicates += pj * dup
+	}
+	return math.Log(pNo), duplicates
+}
+
+// logNoCollision returns the log-probability that s values drawn uniformly
+// from m are all distinct. s need not be an integer.
+func logNoCollision(s, m float64) float64 {
+	if s <= 1 {
+		return 0
+	}
+	if s > m {
+		return math.Inf(-1)
+	}
+	if s/m < 1e-3 {
+		// Σ log(1 - i/m) for i < s by its series: Lgamma would lose
+		// everything to cancellation for large m.
+		pairs := s * (s - 1) / 2
+		return -pairs/m - pairs*(2*s-1)/(6*m*m)
+	}
+	a, _ := math.Lgamma(m + 1)
+	b, _ := math.Lgamma(m - s + 1)
+	return a - b - s*math.Log(m)
+}
+
+// FormatSeconds renders a possibly astronomical number of seconds in the
+// largest unit that keeps it readable.
+func FormatSeconds(s float64) string {
+	const (
+		minute = 60
+		hour   = 60 * minute
+		day    = 24 * hour
+		year   = 365.25 * day
+	)
+	switch {
+	case math.IsInf(s, 1):
+		return "never"
+	case s == 0:
+		return "0s"
+	case s < 1e-3:
+		return fmt.Sprintf("%.3gµs", s*1e6)
+	case s < 1:
+		return fmt.Sprintf("%.3gms", s*1e3)
+	case s < minute:
+		return fmt.Sprintf("%.3gs", s)
+	case s < hour:
+		return fmt.Sprintf("%.1f minutes", s/minute)
+	case s < day:
+		return fmt.Sprintf("%.1f hours", s/hour)
+	case s < year:
+		return fmt.Sprintf("%.1f days", s/day)
+	default:
+		return fmt.Sprintf("%.3g years", s/year)
+	}
+}
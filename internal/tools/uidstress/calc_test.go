@@ -0,0 +1,107 @@
+package uidstress
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestCalcSchemeBirthday checks calcScheme against the birthday
+// approximation P ≈ 1 - exp(-n²/2m) for random schemes, with and without
+// time buckets.
+func TestCalcSchemeBirthday(t *testing.T) {
+	m64 := math.Exp2(64)
+
+	// 1e6 IDs from one node, 64 random bits, no time bucket.
+	cfg := CalcConfig{Rate: 1000, Nodes: 1, Duration: 1000 * time.Second, Target: 0.01}
+	res := calcScheme(cfg, 0, 64, 0, 0)
+	want := 1e12 / (2 * m64)
+	if !closeTo(res.Probability, want, 1e-6) || !closeTo(res.ExpectedDuplicates, want, 1e-6) {
+		t.Errorf("no bucket: P = %g, duplicates %g, want both %g", res.Probability, res.ExpectedDuplicates, want)
+	}
+	if mean := math.Sqrt(math.Pi*m64/2) / 1000; !closeTo(res.MeanTimeToCollision, mean, 1e-9) {
+		t.Errorf("no bucket: mean time to collision %g s, want sqrt(πm/2)/rate = %g s", res.MeanTimeToCollision, mean)
+	}
+	if tt := math.Sqrt(-2*m64*math.Log(0.99)) / 1000; !closeTo(res.TimeToTarget, tt, 1e-9) {
+		t.Errorf("no bucket: time to 1%% %g s, want %g s", res.TimeToTarget, tt)
+	}
+
+	// 4 nodes x 1e4 IDs/s in 1s buckets over 100s, 40 random bits: 4e4 IDs
+	// per bucket and 4e6 in total.
+	m40 := math.Exp2(40)
+	cfg = CalcConfig{Rate: 1e4, Nodes: 4, Duration: 100 * time.Second, Target: 0.01}
+	res = calcScheme(cfg, time.Second, 40, 0, 0)
+	perBucket := 4e4 * 4e4 / (2 * m40)
+	if !closeTo(res.ExpectedDuplicates, 100*perBucket, 1e-3) {
+		t.Errorf("bucketed: duplicates %g, want %g", res.ExpectedDuplicates, 100*perBucket)
+	}
+	if want := -math.Expm1(-100 * perBucket); !closeTo(res.Probability, want, 1e-3) {
+		t.Errorf("bucketed: P = %g, want %g", res.Probability, want)
+	}
+	if want := 1 / -math.Expm1(-perBucket); !closeTo(res.MeanTimeToCollision, want, 1e-3) {
+		t.Errorf("bucketed: mean time to collision %g s, want %g s", res.MeanTimeToCollision, want)
+	}
+	if want := -math.Expm1(-4e6 * 4e6 / (2 * m40)); !closeTo(res.UnbucketedProbability, want, 1e-3) {
+		t.Errorf("bucketed: unbucketed P = %g, want %g", res.UnbucketedProbability, want)
+	}
+}
+
+// TestCalcSchemeNodeIDs checks counter schemes, which collide only between
+// nodes sharing a node ID whose random bases match in the same bucket.
+func TestCalcSchemeNodeIDs(t *testing.T) {
+	m20 := math.Exp2(20)
+	tests := []struct {
+		name       string
+		rate       float64
+		distinct   bool
+		randomBits float64
+		p, dup     float64
+		impossible bool
+	}{
+		// Two busy nodes on the default node ID match with probability
+		// 1/m per bucket, and then repeat each of their 1000 IDs.
+		{"shared", 1000, false, 20, -math.Expm1(1000 * math.Log1p(-1/m20)), 1000 * 1000 / m20, false},
+		// At 0.5 IDs/s both nodes are active in a bucket a quarter of the time.
+		{"shared idle", 0.5, false, 20, -math.Expm1(1000 * math.Log1p(-0.25/m20)), 1000 * 0.25 / m20, false},
+		{"shared no random", 1000, false, 0, 1, 1000 * 1000, false},
+		{"distinct", 1000, true, 20, 0, 0, true},
+	}
+	for _, tt := range tests {
+		cfg := CalcConfig{Rate: tt.rate, Nodes: 2, Duration: 1000 * time.Second, Target: 0.01, DistinctNodeIDs: tt.distinct}
+		res := calcScheme(cfg, time.Second, tt.randomBits, 8, 16)
+		if res.Impossible != tt.impossible {
+			t.Errorf("%s: impossible %t, want %t", tt.name, res.Impossible, tt.impossible)
+		}
+		if !closeTo(res.Probability, tt.p, 1e-6) || !closeTo(res.ExpectedDuplicates, tt.dup, 1e-6) {
+			t.Errorf("%s: P = %g, duplicates %g, want %g and %g", tt.name, res.Probability, res.ExpectedDuplicates, tt.p, tt.dup)
+		}
+	}
+}
+
+// TestNodeGroups checks how nodes are spread over the node IDs.
+func TestNodeGroups(t *testing.T) {
+	tests := []struct {
+		nodes, bits int
+		distinct    bool
+		want        []nodeGroup
+	}{
+		{40, 10, false, []nodeGroup{{size: 40, count: 1}}},
+		{40, 0, true, []nodeGroup{{size: 40, count: 1}}},
+		{40, 10, true, []nodeGroup{{size: 1, count: 40}}},
+		{10, 2, true, []nodeGroup{{size: 3, count: 2}, {size: 2, count: 2}}},
+		{8, 2, true, []nodeGroup{{size: 3, count: 0}, {size: 2, count: 4}}},
+	}
+	for _, tt := range tests {
+		got := nodeGroups(tt.nodes, tt.bits, tt.distinct)
+		if len(got) != len(tt.want) {
+			t.Errorf("nodeGroups(%d, %d, %t) = %v, want %v", tt.nodes, tt.bits, tt.distinct, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("nodeGroups(%d, %d, %t) = %v, want %v", tt.nodes, tt.bits, tt.distinct, got, tt.want)
+				break
+			}
+		}
+	}
+}
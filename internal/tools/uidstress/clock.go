@@ -0,0 +1,160 @@
+package uidstress
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// parseClock builds the clock described by spec for time-based generators.
+// An empty spec or "system" returns nil, meaning the generators keep their
+// own wall clock. Supported specs:
+//
+//	frozen                    time stands still at the moment the run starts
+//	frozen:<RFC 3339>         time stands still at the given instant
+//	skew:jump=-2s,every=N,stall=500ms
+//	                          every N reads the clock jumps by jump (negative
+//	                          is backwards, default -1s) and then reports the
+//	                          same instant for stall of real time (default 0)
+//	step:every=N,by=1s        simulated time: starts at the current time
+//	                          truncated to by and advances by by every N
+//	                          reads, independent of the wall clock
+func parseClock(spec string) (tools.Clock, error) {
+	kind, args, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch strings.ToLower(kind) {
+	case "", "system":
+		return nil, nil
+	case "frozen":
+		at := time.Now()
+		if args != "" {
+			var err error
+			if at, err = time.Parse(time.RFC3339Nano, args); err != nil {
+				return nil, fmt.Errorf("clock %q: %w", spec, err)
+			}
+		}
+		return tools.ClockFunc(func() time.Time { return at }), nil
+	case "skew":
+		c := &skewClock{jump: -time.Second, every: 1_000_000}
+		for _, part := range strings.Split(args, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(part, "=")
+			if !ok {
+				return nil, fmt.Errorf("clock %q: %q is not key=value", spec, part)
+			}
+			var err error
+			switch key {
+			case "jump":
+				c.jump, err = time.ParseDuration(value)
+			case "stall":
+				c.stall, err = time.ParseDuration(value)
+			case "every":
+				c.every, err = strconv.ParseInt(value, 10, 64)
+				if err == nil && c.every <= 0 {
+					err = fmt.Errorf("every must be > 0")
+				}
+			default:
+				err = fmt.Errorf("unknown key %q", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("clock %q: %w", spec, err)
+			}
+		}
+		return c, nil
+	case "step":
+		c := &stepClock{by: time.Second, every: 1_000_000}
+		for _, part := range strings.Split(args, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(part, "=")
+			if !ok {
+				return nil, fmt.Errorf("clock %q: %q is not key=value", spec, part)
+			}
+			var err error
+			switch key {
+			case "by":
+				c.by, err = time.ParseDuration(value)
+				if err == nil && c.by <= 0 {
+					err = fmt.Errorf("by must be > 0")
+				}
+			case "every":
+				c.every, err = strconv.ParseInt(value, 10, 64)
+				if err == nil && c.every <= 0 {
+					err = fmt.Errorf("every must be > 0")
+				}
+			default:
+				err = fmt.Errorf("unknown key %q", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("clock %q: %w", spec, err)
+			}
+		}
+		c.start = time.Now().Truncate(c.by)
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unknown clock %q (want system, frozen, skew or step)", spec)
+	}
+}
+
+// skewClock follows the wall clock plus an offset that changes by jump every
+// every reads, optionally freezing for stall after each jump. It simulates
+// NTP steps and clock rollback for the generators reading it.
+type skewClock struct {
+	jump  time.Duration
+	every int64
+	stall time.Duration
+
+	reads atomic.Int64
+
+	mu         sync.Mutex
+	offset     time.Duration
+	stallUntil time.Time // wall time at which the current stall ends
+	stallAt    time.Time // instant reported while stalled
+}
+
+func (c *skewClock) Now() time.Time {
+	n := c.reads.Add(1)
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n%c.every == 0 {
+		c.offset += c.jump
+		if c.stall > 0 {
+			c.stallAt = now.Add(c.offset)
+			c.stallUntil = now.Add(c.stall)
+		}
+	}
+	if now.Before(c.stallUntil) {
+		return c.stallAt
+	}
+	return now.Add(c.offset)
+}
+
+// stepClock is a simulated clock that only moves when it is read: every
+// every reads it advances by by. It lets a run offer an exact number of IDs
+// per tick no matter how fast the machine generates them.
+type stepClock struct {
+	start time.Time
+	by    time.Duration
+	every int64
+
+	reads atomic.Int64
+}
+
+func (c *stepClock) Now() time.Time {
+	n := c.reads.Add(1) - 1
+	return c.start.Add(time.Duration(n/c.every) * c.by)
+}
+
+// tick returns the number of times the clock has advanced so far.
+func (c *stepClock) tick() int64 {
+	return max(c.reads.Load()-1, 0) / c.every
+}
@@ -0,0 +1,91 @@
+package uidstress
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// occurrence locates one copy of an ID: the run it came from (only set when
+// several runs are merged), the chunk it was generated in and the 1-based
+// line of that chunk file holding it. Copies that collided inside a single
+// chunk share the line of the one copy that was written.
+type occurrence struct {
+	Source string `json:"source,omitempty"`
+	Chunk  int    `json:"chunk"`
+	Line   int64  `json:"line"`
+}
+
+// duplicateRecord is one line of the duplicates report.
+type duplicateRecord struct {
+	ID          string       `json:"id"`
+	Count       int          `json:"count"`
+	Occurrences []occurrence `json:"occurrences"`
+	Timestamp   *time.Time   `json:"timestamp,omitempty"`
+}
+
+// duplicateReport writes colliding IDs as NDJSON. The file is only created
+// once the first duplicate is recorded. A nil report discards everything.
+type duplicateReport struct {
+	path    string
+	decoder tools.TimeDecoder
+
+	file        *os.File
+	writer      *bufio.Writer
+	enc         *json.Encoder
+	records     int64
+	crossSource int64 // runs beyond the first producing each recorded ID
+}
+
+func newDuplicateReport(path string, decoder tools.TimeDecoder) *duplicateReport {
+	return &duplicateReport{path: path, decoder: decoder}
+}
+
+// add records id if it occurred more than once across occs, which holds
+// one entry per copy, including copies dropped inside a chunk.
+func (r *duplicateReport) add(id string, occs []occurrence) error {
+	if r == nil || len(occs) < 2 {
+		return nil
+	}
+	sources := make(map[string]bool, len(occs))
+	for _, occ := range occs {
+		sources[occ.Source] = true
+	}
+	r.crossSource += int64(len(sources) - 1)
+
+	rec := duplicateRecord{ID: id, Count: len(occs), Occurrences: occs}
+	if r.decoder != nil {
+		if ts, err := r.decoder.DecodeTime(id); err == nil {
+			rec.Timestamp = &ts
+		}
+	}
+	if r.file == nil {
+		f, err := os.Create(r.path)
+		if err != nil {
+			return err
+		}
+		r.file = f
+		r.writer = bufio.NewWriter(f)
+		r.enc = json.NewEncoder(r.writer)
+	}
+	r.records++
+	return r.enc.Encode(rec)
+}
+
+func (r *duplicateReport) close() error {
+	if r == nil || r.file == nil {
+		return nil
+	}
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	if err := r.file.Sync(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,126 @@
+package uidstress
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// Environment describes the machine, Go runtime and binary a run was made
+// with, so archived runs can be compared meaningfully. Fields that cannot
+// be read on the current platform are left empty.
+type Environment struct {
+	Hostname      string `json:"hostname,omitempty"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+	Platform      string `json:"platform,omitempty"`
+	KernelVersion string `json:"kernel_version,omitempty"`
+	CPUModel      string `json:"cpu_model,omitempty"`
+	PhysicalCores int    `json:"physical_cores,omitempty"`
+	LogicalCores  int    `json:"logical_cores"`
+	MemoryBytes   uint64 `json:"memory_bytes,omitempty"`
+	GoVersion     string `json:"go_version"`
+	GOMAXPROCS    int    `json:"gomaxprocs"`
+	// GOGC is the GOGC setting ("100" when unset, "off" when disabled).
+	GOGC string `json:"gogc"`
+	// GOMemLimit is the soft memory limit in bytes, 0 when there is none.
+	GOMemLimit int64 `json:"gomemlimit,omitempty"`
+	// Revision is the VCS revision the binary was built from, with Dirty
+	// set when the working tree had uncommitted changes. Both are empty for
+	// binaries built without VCS stamping (e.g. go run).
+	Revision string `json:"revision,omitempty"`
+	Dirty    bool   `json:"dirty,omitempty"`
+}
+
+// CurrentEnvironment describes the running process.
+func CurrentEnvironment() Environment {
+	env := Environment{
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		LogicalCores: runtime.NumCPU(),
+		GoVersion:    runtime.Version(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		GOGC:         os.Getenv("GOGC"),
+	}
+	if env.GOGC == "" {
+		env.GOGC = "100"
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		env.GOMemLimit = limit
+	}
+
+	if info, err := host.Info(); err == nil {
+		env.Hostname = info.Hostname
+		env.Platform = strings.TrimSpace(info.Platform + " " + info.PlatformVersion)
+		env.KernelVersion = info.KernelVersion
+	} else if hostname, err := os.Hostname(); err == nil {
+		env.Hostname = hostname
+	}
+	if infos, err := cpu.Info(); err == nil && len(infos) > 0 {
+		env.CPUModel = strings.TrimSpace(infos[0].ModelName)
+	}
+	if n, err := cpu.Counts(false); err == nil {
+		env.PhysicalCores = n
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		env.MemoryBytes = vm.Total
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				env.Revision = s.Value
+			case "vcs.modified":
+				env.Dirty = s.Value == "true"
+			}
+		}
+	}
+	return env
+}
+
+// String summarizes the environment on one line.
+func (e Environment) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s/%s", e.OS, e.Arch)
+	switch {
+	case e.Platform != "" && e.KernelVersion != "":
+		fmt.Fprintf(&b, " (%s, kernel %s)", e.Platform, e.KernelVersion)
+	case e.Platform != "":
+		fmt.Fprintf(&b, " (%s)", e.Platform)
+	case e.KernelVersion != "":
+		fmt.Fprintf(&b, " (kernel %s)", e.KernelVersion)
+	}
+	if e.CPUModel != "" {
+		fmt.Fprintf(&b, ", %s", e.CPUModel)
+	}
+	fmt.Fprintf(&b, ", %d cores", e.LogicalCores)
+	if e.PhysicalCores > 0 && e.PhysicalCores != e.LogicalCores {
+		fmt.Fprintf(&b, " (%d physical)", e.PhysicalCores)
+	}
+	if e.MemoryBytes > 0 {
+		fmt.Fprintf(&b, ", %.1f GiB", float64(e.MemoryBytes)/(1<<30))
+	}
+	fmt.Fprintf(&b, ", %s, GOMAXPROCS=%d, GOGC=%s", e.GoVersion, e.GOMAXPROCS, e.GOGC)
+	if e.GOMemLimit > 0 {
+		fmt.Fprintf(&b, ", GOMEMLIMIT=%d", e.GOMemLimit)
+	}
+	if e.Revision != "" {
+		rev := e.Revision
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		if e.Dirty {
+			rev += "-dirty"
+		}
+		fmt.Fprintf(&b, ", rev %s", rev)
+	}
+	return b.String()
+}
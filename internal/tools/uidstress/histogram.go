@@ -0,0 +1,155 @@
+package uidstress
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"time"
+)
+
+// histSubBits is the number of linear sub-buckets per power of two, as a
+// bit count. 7 bits keep every recorded value within 1/128 (< 0.8%) of the
+// bucket it is reported as.
+const histSubBits = 7
+
+const (
+	histSubCount = 1 << histSubBits
+	histBuckets  = (64 - histSubBits + 1) << histSubBits
+)
+
+// Histogram is an HDR-style log-linear histogram of non-negative int64
+// values (nanoseconds here): values below 128 are counted exactly, larger
+// ones in 128 linear buckets per power of two. Histograms are not safe for
+// concurrent use; give each worker its own and Merge them.
+type Histogram struct {
+	counts [histBuckets]int64
+	total  int64
+	max    int64
+}
+
+// histIndex returns the bucket of v.
+func histIndex(v int64) int {
+	if v < histSubCount {
+		return int(max(v, 0))
+	}
+	shift := bits.Len64(uint64(v)) - histSubBits - 1
+	return (shift+1)<<histSubBits + int(v>>shift) - histSubCount
+}
+
+// histUpper returns the largest value counted in bucket i.
+func histUpper(i int) int64 {
+	if i < histSubCount {
+		return int64(i)
+	}
+	shift := i>>histSubBits - 1
+	low := int64(i&(histSubCount-1)+histSubCount) << shift
+	return low + int64(1)<<shift - 1
+}
+
+// Record adds one value.
+func (h *Histogram) Record(v int64) {
+	h.counts[histIndex(v)]++
+	h.total++
+	h.max = max(h.max, v)
+}
+
+// Merge adds every value recorded in o.
+func (h *Histogram) Merge(o *Histogram) {
+	if o == nil {
+		return
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+	h.max = max(h.max, o.max)
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() int64 { return h.total }
+
+// Quantile returns the value at quantile q (0 < q <= 1), reported as the
+// upper bound of its bucket but never above the largest recorded value.
+func (h *Histogram) Quantile(q float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	rank := int64(q * float64(h.total))
+	rank = min(max(rank, 1), h.total)
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return min(histUpper(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// histogramJSON stores only the non-empty buckets, as [bucket, count] pairs.
+type histogramJSON struct {
+	SubBits int        `json:"sub_bits"`
+	Max     int64      `json:"max"`
+	Counts  [][2]int64 `json:"counts"`
+}
+
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	out := histogramJSON{SubBits: histSubBits, Max: h.max, Counts: [][2]int64{}}
+	for i, c := range h.counts {
+		if c > 0 {
+			out.Counts = append(out.Counts, [2]int64{int64(i), c})
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var in histogramJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.SubBits != histSubBits {
+		return fmt.Errorf("histogram has %d sub-bucket bits, want %d", in.SubBits, histSubBits)
+	}
+	*h = Histogram{max: in.Max}
+	for _, pair := range in.Counts {
+		if pair[0] < 0 || pair[0] >= histBuckets || pair[1] < 0 {
+			return fmt.Errorf("invalid histogram bucket %v", pair)
+		}
+		h.counts[pair[0]] += pair[1]
+		h.total += pair[1]
+	}
+	return nil
+}
+
+// Latency summarizes sampled per-ID generation latencies.
+type Latency struct {
+	Samples int64         `json:"samples"`
+	P50     time.Duration `json:"p50_ns"`
+	P90     time.Duration `json:"p90_ns"`
+	P99     time.Duration `json:"p99_ns"`
+	P999    time.Duration `json:"p999_ns"`
+	Max     time.Duration `json:"max_ns"`
+}
+
+// Latency summarizes h, read as nanoseconds. It returns nil for a nil or
+// empty histogram.
+func (h *Histogram) Latency() *Latency {
+	if h == nil || h.total == 0 {
+		return nil
+	}
+	return &Latency{
+		Samples: h.total,
+		P50:     time.Duration(h.Quantile(0.50)),
+		P90:     time.Duration(h.Quantile(0.90)),
+		P99:     time.Duration(h.Quantile(0.99)),
+		P999:    time.Duration(h.Quantile(0.999)),
+		Max:     time.Duration(h.max),
+	}
+}
+
+// String formats the percentiles on one line.
+func (l Latency) String() string {
+	return fmt.Sprintf("p50 %s, p90 %s, p99 %s, p99.9 %s, max %s (%d samples)",
+		l.P50, l.P90, l.P99, l.P999, l.Max, l.Samples)
+}
@@ -0,0 +1,156 @@
+package uidstress
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestHistIndexBoundaries checks that each value falls in a bucket whose
+// range contains it, that bucket ranges are contiguous, and that the range
+// is at most 1/128 of the value.
+func TestHistIndexBoundaries(t *testing.T) {
+	tests := []struct {
+		v     int64
+		index int
+		upper int64
+	}{
+		{-5, 0, 0},
+		{0, 0, 0},
+		{1, 1, 1},
+		{127, 127, 127},
+		{128, 128, 128},
+		{255, 255, 255},
+		{256, 256, 257},
+		{257, 256, 257},
+		{258, 257, 259},
+		{511, 383, 511},
+		{512, 384, 515},
+		{math.MaxInt64, histBuckets - 129, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		i := histIndex(tt.v)
+		if i != tt.index || histUpper(i) != tt.upper {
+			t.Errorf("histIndex(%d) = %d with upper %d, want %d with upper %d", tt.v, i, histUpper(i), tt.index, tt.upper)
+		}
+	}
+
+	for _, v := range []int64{2, 100, 128, 1000, 4095, 4096, 1e6, 123456789, 1e12, 1 << 62} {
+		for _, v := range []int64{v - 1, v, v + 1} {
+			i := histIndex(v)
+			if i <= 0 || i >= histBuckets {
+				t.Fatalf("histIndex(%d) = %d out of range", v, i)
+			}
+			if up, prev := histUpper(i), histUpper(i-1); v > up || v <= prev {
+				t.Errorf("value %d in bucket %d covering (%d, %d]", v, i, prev, up)
+			}
+			if width := histUpper(i) - histUpper(i-1); width > max(v/histSubCount, 1) {
+				t.Errorf("bucket %d of value %d is %d wide, more than 1/%d", i, v, width, histSubCount)
+			}
+		}
+	}
+}
+
+// TestHistogramEmpty checks that an empty histogram reports zeros.
+func TestHistogramEmpty(t *testing.T) {
+	var h Histogram
+	if h.Count() != 0 || h.Quantile(0.5) != 0 || h.Quantile(1) != 0 {
+		t.Errorf("empty histogram: count %d, p50 %d, p100 %d", h.Count(), h.Quantile(0.5), h.Quantile(1))
+	}
+	if l := h.Latency(); l != nil {
+		t.Errorf("empty histogram latency = %+v, want nil", l)
+	}
+	var nilHist *Histogram
+	if l := nilHist.Latency(); l != nil {
+		t.Errorf("nil histogram latency = %+v, want nil", l)
+	}
+}
+
+// TestHistogramSingleSample checks that every quantile of one value is that
+// value, not the upper bound of its bucket.
+func TestHistogramSingleSample(t *testing.T) {
+	var h Histogram
+	h.Record(123457)
+	for _, q := range []float64{0.001, 0.5, 0.99, 0.999, 1} {
+		if got := h.Quantile(q); got != 123457 {
+			t.Errorf("Quantile(%g) = %d, want 123457", q, got)
+		}
+	}
+	want := Latency{Samples: 1, P50: 123457, P90: 123457, P99: 123457, P999: 123457, Max: 123457}
+	if l := h.Latency(); l == nil || *l != want {
+		t.Errorf("Latency() = %+v, want %+v", l, want)
+	}
+}
+
+// TestHistogramUniformQuantiles checks p50 to p99.9 of the values 1..100000
+// against their exact values.
+func TestHistogramUniformQuantiles(t *testing.T) {
+	const n = 100_000
+	var h Histogram
+	for v := int64(1); v <= n; v++ {
+		h.Record(v)
+	}
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999} {
+		exact := int64(q * n)
+		got := h.Quantile(q)
+		if got < exact || float64(got-exact) > float64(exact)/histSubCount {
+			t.Errorf("Quantile(%g) = %d, want within 1/%d above %d", q, got, histSubCount, exact)
+		}
+	}
+	if got := h.Quantile(1); got != n {
+		t.Errorf("Quantile(1) = %d, want %d", got, n)
+	}
+}
+
+// TestHistogramMerge checks that merging per-worker histograms gives the
+// same counts as recording every value in one.
+func TestHistogramMerge(t *testing.T) {
+	var all, even, odd Histogram
+	for v := int64(0); v < 50_000; v++ {
+		d := v * v % 1_000_003
+		all.Record(d)
+		if v%2 == 0 {
+			even.Record(d)
+		} else {
+			odd.Record(d)
+		}
+	}
+	var merged Histogram
+	merged.Merge(&even)
+	merged.Merge(&odd)
+	merged.Merge(nil)
+	if merged != all {
+		t.Fatalf("merged histogram differs: count %d max %d, want count %d max %d",
+			merged.Count(), merged.max, all.Count(), all.max)
+	}
+	if *merged.Latency() != *all.Latency() {
+		t.Errorf("merged latency %v, want %v", merged.Latency(), all.Latency())
+	}
+}
+
+// TestHistogramJSON checks that a histogram survives a JSON round trip and
+// that buckets from another resolution are rejected.
+func TestHistogramJSON(t *testing.T) {
+	var h Histogram
+	for _, d := range []time.Duration{80, 900, 15 * time.Microsecond, 2 * time.Millisecond, time.Second} {
+		h.Record(int64(d))
+	}
+	data, err := json.Marshal(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Histogram
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != h {
+		t.Errorf("round trip of %s changed the histogram", data)
+	}
+	if err := json.Unmarshal([]byte(`{"sub_bits":5,"max":1,"counts":[[1,1]]}`), &back); err == nil {
+		t.Error("histogram with 5 sub-bucket bits accepted")
+	}
+	if err := json.Unmarshal([]byte(`{"sub_bits":7,"max":1,"counts":[[-1,1]]}`), &back); err == nil {
+		t.Error("histogram with a negative bucket accepted")
+	}
+}
@@ -0,0 +1,250 @@
+package uidstress
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HistoryRecord is one line of the results history: one scheme of one run.
+type HistoryRecord struct {
+	// RunID groups the records written by one command invocation.
+	RunID     string    `json:"run_id"`
+	Command   string    `json:"command"`
+	CreatedAt time.Time `json:"created_at"`
+	Scheme    string    `json:"scheme"`
+	Scale     int64     `json:"scale"`
+	// Duration is the configured time budget of duration-bounded runs,
+	// whose Scale (the IDs generated) varies from run to run.
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	// Settings lists the configuration that affects the result (workers,
+	// rate, layout, clock, truncation and so on), see historySettings.
+	Settings    string      `json:"settings,omitempty"`
+	Fingerprint string      `json:"fingerprint"`
+	Environment Environment `json:"environment"`
+	Result      Result      `json:"result"`
+}
+
+// Throughput returns the generated IDs per second of generation time of
+// the record's run, so that sorting and merging do not dilute it. Records
+// written before generation time was kept fall back to the whole duration.
+func (r HistoryRecord) Throughput() float64 {
+	d := r.Result.GenerateTime
+	if d <= 0 {
+		d = r.Result.Duration
+	}
+	if d <= 0 {
+		return 0
+	}
+	return float64(r.Result.Generated) / d.Seconds()
+}
+
+// key identifies records that measure the same thing and can be compared.
+// Duration-bounded runs are matched by duration instead of scale.
+func (r HistoryRecord) key() string {
+	size := fmt.Sprintf("n=%d", r.Scale)
+	if r.Duration > 0 {
+		size = "d=" + r.Duration.String()
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s", r.Command, r.Scheme, size, r.Settings, r.Fingerprint)
+}
+
+// historySettings returns the settings and time budget recorded for the
+// config of a report; commands without such settings (merge) get none.
+func historySettings(cfg any) (string, time.Duration) {
+	switch c := cfg.(type) {
+	case Config:
+		return c.settings(), c.Duration
+	case MultiProcConfig:
+		return fmt.Sprintf("%s,procs=%d,distinct_node_ids=%t", c.Config.settings(), c.Procs, c.DistinctNodeIDs), c.Duration
+	}
+	return "", 0
+}
+
+// settings lists the fields of c that change what a run measures.
+func (c Config) settings() string {
+	return fmt.Sprintf("workers=%d,chunk=%d,rate=%g,node_id=%d,layout=%s,clock=%s,truncate_bits=%d,latency_sample=%d,track_order=%t",
+		c.Workers, c.ChunkSize, c.Rate, c.NodeID, c.Layout, c.Clock, c.TruncateBits, c.LatencySample, c.TrackOrder)
+}
+
+// Fingerprint hashes the parts of the environment that affect performance.
+// Hostname, kernel and revision are left out so that the same hardware and
+// runtime compare equal across machines of one type and across commits.
+func (e Environment) Fingerprint() string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%s|%d|%d|%d|%s|%d|%s|%d",
+		e.OS, e.Arch, e.CPUModel, e.PhysicalCores, e.LogicalCores, e.MemoryBytes,
+		e.GoVersion, e.GOMAXPROCS, e.GOGC, e.GOMemLimit))
+	return hex.EncodeToString(sum[:6])
+}
+
+// AppendHistory appends one record per result of rep to the JSON-lines
+// file at path, creating it if needed, and returns the run ID used.
+func AppendHistory(path string, rep Report) (string, error) {
+	var suffix [2]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", err
+	}
+	runID := rep.CreatedAt.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix[:])
+	settings, duration := historySettings(rep.Config)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, res := range rep.Results {
+		env := rep.Environment
+		if res.Environment != nil {
+			env = *res.Environment
+		}
+		res.Environment = nil
+		rec := HistoryRecord{
+			RunID:       runID,
+			Command:     rep.Command,
+			CreatedAt:   rep.CreatedAt,
+			Scheme:      res.Scheme,
+			Scale:       res.Generated,
+			Duration:    duration,
+			Settings:    settings,
+			Fingerprint: env.Fingerprint(),
+			Environment: env,
+			Result:      res,
+		}
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return "", err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return "", err
+	}
+	return runID, f.Close()
+}
+
+// LoadHistory reads every record of the history file in the order written.
+func LoadHistory(path string) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec HistoryRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, sc.Err()
+}
+
+// Comparison is the throughput change of one scheme between two runs.
+type Comparison struct {
+	Scheme   string
+	Baseline HistoryRecord
+	Current  HistoryRecord
+	// Change is the relative throughput change, e.g. -0.12 for 12% slower.
+	Change float64
+	// Regressed is set when throughput dropped by more than the tolerance.
+	Regressed bool
+	// Mismatch explains why the runs are not strictly comparable (different
+	// scale, duration, settings or environment fingerprint); empty when
+	// they are.
+	Mismatch string
+}
+
+// ErrNoBaseline is returned by Compare when no earlier run matches.
+var ErrNoBaseline = errors.New("no baseline run to compare with")
+
+// Compare compares run against baseline, scheme by scheme. An empty run
+// means the latest run in records. An empty baseline means, for each
+// scheme, the latest earlier record of the same command, scheme, scale (or
+// duration), settings and environment fingerprint. tolerance is the
+// accepted relative slowdown, e.g. 0.05 for 5%.
+func Compare(records []HistoryRecord, baseline, run string, tolerance float64) ([]Comparison, error) {
+	if len(records) == 0 {
+		return nil, errors.New("history is empty")
+	}
+	if run == "" {
+		run = records[len(records)-1].RunID
+	}
+	current := recordsOf(records, run)
+	if len(current) == 0 {
+		return nil, fmt.Errorf("run %s not found in history", run)
+	}
+
+	var comparisons []Comparison
+	for _, cur := range current {
+		var (
+			base  HistoryRecord
+			found bool
+		)
+		if baseline != "" {
+			for _, rec := range recordsOf(records, baseline) {
+				if rec.Scheme == cur.Scheme {
+					base, found = rec, true
+				}
+			}
+		} else {
+			for _, rec := range records {
+				if rec.RunID == run {
+					break
+				}
+				if rec.key() == cur.key() {
+					base, found = rec, true
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+
+		c := Comparison{Scheme: cur.Scheme, Baseline: base, Current: cur}
+		if bt := base.Throughput(); bt > 0 {
+			c.Change = cur.Throughput()/bt - 1
+			c.Regressed = c.Change < -tolerance
+		}
+		switch {
+		case base.Command != cur.Command:
+			c.Mismatch = fmt.Sprintf("command %s vs %s", base.Command, cur.Command)
+		case base.Duration != cur.Duration:
+			c.Mismatch = fmt.Sprintf("duration %s vs %s", base.Duration, cur.Duration)
+		case base.Duration == 0 && base.Scale != cur.Scale:
+			c.Mismatch = fmt.Sprintf("scale %d vs %d", base.Scale, cur.Scale)
+		case base.Settings != cur.Settings:
+			c.Mismatch = fmt.Sprintf("settings %s vs %s", base.Settings, cur.Settings)
+		case base.Fingerprint != cur.Fingerprint:
+			c.Mismatch = fmt.Sprintf("environment %s vs %s", base.Fingerprint, cur.Fingerprint)
+		}
+		comparisons = append(comparisons, c)
+	}
+	if len(comparisons) == 0 {
+		return nil, fmt.Errorf("%w: run %s", ErrNoBaseline, run)
+	}
+	return comparisons, nil
+}
+
+func recordsOf(records []HistoryRecord, runID string) []HistoryRecord {
+	var out []HistoryRecord
+	for _, rec := range records {
+		if rec.RunID == runID {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
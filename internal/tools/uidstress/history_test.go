@@ -0,0 +1,192 @@
+package uidstress
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// historyRecord returns a stress record of run for scheme that generated n
+// IDs in d of generation time and 2d overall.
+func historyRecord(run, scheme string, n int64, d time.Duration) HistoryRecord {
+	return HistoryRecord{
+		RunID:       run,
+		Command:     "stress",
+		Scheme:      scheme,
+		Scale:       n,
+		Settings:    "workers=1",
+		Fingerprint: "f1",
+		Result:      Result{Scheme: scheme, Generated: n, GenerateTime: d, Duration: 2 * d},
+	}
+}
+
+// TestCompareBaselineKey checks that the default baseline of each scheme is
+// the latest earlier record with the same command, scale, settings and
+// fingerprint, and that records of other runs are not mixed in.
+func TestCompareBaselineKey(t *testing.T) {
+	other := historyRecord("r2", "ulid", 1000, time.Second)
+	other.Settings = "workers=8"
+	otherScale := historyRecord("r3", "ulid", 2000, time.Second)
+	otherEnv := historyRecord("r4", "ulid", 1000, time.Second)
+	otherEnv.Fingerprint = "f2"
+	records := []HistoryRecord{
+		historyRecord("r1", "ulid", 1000, time.Second),
+		historyRecord("r1", "ksuid", 1000, time.Second),
+		other, otherScale, otherEnv,
+		historyRecord("r5", "ulid", 1000, 2*time.Second),
+		historyRecord("r5", "ksuid", 1000, time.Second),
+		historyRecord("r5", "nanoid", 1000, time.Second),
+		// Recorded after the run being compared: never a baseline.
+		historyRecord("r6", "ksuid", 1000, time.Second/2),
+	}
+	comparisons, err := Compare(records, "", "r5", 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nanoid has no earlier run and is left out.
+	want := map[string]struct {
+		baseline  string
+		change    float64
+		regressed bool
+	}{
+		"ulid":  {"r1", -0.5, true},
+		"ksuid": {"r1", 0, false},
+	}
+	if len(comparisons) != len(want) {
+		t.Fatalf("got %d comparisons, want %d: %+v", len(comparisons), len(want), comparisons)
+	}
+	for _, c := range comparisons {
+		w := want[c.Scheme]
+		if c.Baseline.RunID != w.baseline || c.Current.RunID != "r5" || c.Change != w.change || c.Regressed != w.regressed || c.Mismatch != "" {
+			t.Errorf("%s: baseline %s, run %s, change %g, regressed %t, mismatch %q; want %s, r5, %g, %t, none",
+				c.Scheme, c.Baseline.RunID, c.Current.RunID, c.Change, c.Regressed, c.Mismatch, w.baseline, w.change, w.regressed)
+		}
+	}
+
+	// Without a run the latest one is compared.
+	comparisons, err = Compare(records, "", "", 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparisons) != 1 || comparisons[0].Baseline.RunID != "r5" || comparisons[0].Change != 1 {
+		t.Errorf("latest run: %+v, want ksuid against r5 at +100%%", comparisons)
+	}
+}
+
+// TestCompareMismatch checks that an explicit baseline is compared even
+// when it measured something else, and that the difference is reported.
+func TestCompareMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*HistoryRecord)
+		want   string
+	}{
+		{"same", func(*HistoryRecord) {}, ""},
+		{"command", func(r *HistoryRecord) { r.Command = "multiproc" }, "command multiproc vs stress"},
+		{"duration", func(r *HistoryRecord) { r.Duration = time.Minute }, "duration 1m0s vs 0s"},
+		{"scale", func(r *HistoryRecord) { r.Scale = 500 }, "scale 500 vs 1000"},
+		{"settings", func(r *HistoryRecord) { r.Settings = "workers=8" }, "settings workers=8 vs workers=1"},
+		{"environment", func(r *HistoryRecord) { r.Fingerprint = "f2" }, "environment f2 vs f1"},
+	}
+	for _, tt := range tests {
+		base := historyRecord("base", "ulid", 1000, time.Second)
+		tt.change(&base)
+		records := []HistoryRecord{base, historyRecord("run", "ulid", 1000, time.Second)}
+		comparisons, err := Compare(records, "base", "run", 0.05)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(comparisons) != 1 || comparisons[0].Mismatch != tt.want {
+			t.Errorf("%s: %+v, want mismatch %q", tt.name, comparisons, tt.want)
+		}
+	}
+
+	// Runs of the same duration match whatever number of IDs they reached.
+	base := historyRecord("base", "ulid", 900, time.Second)
+	run := historyRecord("run", "ulid", 1000, time.Second)
+	base.Duration, run.Duration = time.Second, time.Second
+	comparisons, err := Compare([]HistoryRecord{base, run}, "", "run", 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comparisons[0].Mismatch != "" {
+		t.Errorf("duration-bounded runs: mismatch %q, want none", comparisons[0].Mismatch)
+	}
+}
+
+// TestHistoryThroughput checks that throughput is measured over generation
+// time and falls back to the whole duration for older records.
+func TestHistoryThroughput(t *testing.T) {
+	tests := []struct {
+		generate, duration time.Duration
+		want               float64
+	}{
+		{time.Second, 4 * time.Second, 1000},
+		{0, 4 * time.Second, 250},
+		{0, 0, 0},
+	}
+	for _, tt := range tests {
+		rec := HistoryRecord{Result: Result{Generated: 1000, GenerateTime: tt.generate, Duration: tt.duration}}
+		if got := rec.Throughput(); got != tt.want {
+			t.Errorf("Throughput(1000 IDs, generate %s, duration %s) = %g, want %g", tt.generate, tt.duration, got, tt.want)
+		}
+	}
+
+	// A baseline without throughput gives no change and no regression.
+	base := historyRecord("base", "ulid", 1000, 0)
+	comparisons, err := Compare([]HistoryRecord{base, historyRecord("run", "ulid", 1000, time.Second)}, "", "run", 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := comparisons[0]; c.Change != 0 || c.Regressed {
+		t.Errorf("baseline without throughput: change %g, regressed %t; want 0, false", c.Change, c.Regressed)
+	}
+}
+
+// TestCompareTolerance checks that a slowdown of exactly the tolerance is
+// accepted and anything beyond it is a regression. The throughputs are
+// exact in binary floating point.
+func TestCompareTolerance(t *testing.T) {
+	tests := []struct {
+		generated int64
+		regressed bool
+	}{
+		{1500, false},
+		{1000, false},
+		{750, false},
+		{749, true},
+		{0, true},
+	}
+	for _, tt := range tests {
+		records := []HistoryRecord{
+			historyRecord("base", "ulid", 1000, time.Second),
+			historyRecord("run", "ulid", 1000, time.Second),
+		}
+		records[1].Result.Generated = tt.generated
+		comparisons, err := Compare(records, "", "run", 0.25)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := comparisons[0]; c.Regressed != tt.regressed {
+			t.Errorf("%d IDs/s against 1000 IDs/s at 25%% tolerance: change %g, regressed %t, want %t",
+				tt.generated, c.Change, c.Regressed, tt.regressed)
+		}
+	}
+}
+
+// TestCompareErrors checks the errors for an empty history, an unknown run
+// and a run without any baseline.
+func TestCompareErrors(t *testing.T) {
+	records := []HistoryRecord{historyRecord("r1", "ulid", 1000, time.Second)}
+	if _, err := Compare(nil, "", "", 0.05); err == nil {
+		t.Error("Compare(empty history) succeeded")
+	}
+	if _, err := Compare(records, "", "r9", 0.05); err == nil || !strings.Contains(err.Error(), "r9") {
+		t.Errorf("Compare(unknown run) = %v, want an error naming r9", err)
+	}
+	if _, err := Compare(records, "", "", 0.05); !errors.Is(err, ErrNoBaseline) {
+		t.Errorf("Compare(first run) = %v, want ErrNoBaseline", err)
+	}
+}
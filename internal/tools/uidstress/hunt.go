@@ -0,0 +1,354 @@
+package uidstress
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// HuntConfig controls a collision hunt: a fresh generator produces IDs
+// until the first duplicate, and the number of IDs that took is recorded
+// over many trials.
+type HuntConfig struct {
+	// Scheme is the scheme to hunt. Hunts only finish in reasonable time
+	// for small ID spaces, such as short nanoids or reduced customuid
+	// layouts.
+	Scheme string `json:"scheme"`
+	// Length replaces the nanoid length (tools.NewNanoIdGenerator); 0
+	// keeps the registered length. Other schemes reject it.
+	Length int    `json:"length,omitempty"`
+	NodeID int64  `json:"node_id,omitempty"`
+	Layout string `json:"layout,omitempty"`
+	// Clock is the clock of time-based schemes (default "frozen"), see
+	// parseClock. The expectation assumes every ID of a trial falls in
+	// one time unit, which a frozen clock guarantees.
+	Clock  string `json:"clock"`
+	Trials int    `json:"trials"`
+	// MaxIDs stops a trial without a duplicate after this many IDs; such
+	// trials are counted as censored and left out of the distribution.
+	MaxIDs     int64   `json:"max_ids"`
+	MemGuardMB float64 `json:"mem_guard_mb"`
+	Verbose    bool    `json:"verbose"`
+}
+
+// HuntResult compares the observed number of IDs until the first
+// duplicate with the birthday expectation.
+type HuntResult struct {
+	Scheme string `json:"scheme"`
+	// SpaceBits is log2 of the number of values each random draw picks
+	// from, and IDsPerDraw the number of IDs one draw covers: 1 for
+	// purely random schemes, the counter range for customuid, whose
+	// random base is drawn again whenever the counter overflows.
+	SpaceBits  float64 `json:"space_bits"`
+	IDsPerDraw int64   `json:"ids_per_draw"`
+	// SingleTimeUnit is set when every ID of a trial shares one time unit
+	// (no timestamp, or a frozen clock), which the expectation assumes.
+	SingleTimeUnit bool `json:"single_time_unit"`
+	Trials         int  `json:"trials"`
+	Censored       int  `json:"censored"`
+	// Counts holds the IDs generated up to and including the first
+	// duplicate, for every completed trial in order.
+	Counts   []int64 `json:"counts"`
+	Observed Summary `json:"observed"`
+	Min      int64   `json:"min"`
+	Max      int64   `json:"max"`
+	// Expected and ExpectedStdDev are the exact mean and standard
+	// deviation of Counts; SqrtApprox is the classic sqrt(πM/2)
+	// approximation of the mean, scaled by IDsPerDraw.
+	Expected       float64 `json:"expected"`
+	ExpectedStdDev float64 `json:"expected_stddev"`
+	SqrtApprox     float64 `json:"sqrt_pi_m_over_2"`
+	// KS is the Kolmogorov-Smirnov distance between Counts and the
+	// expected distribution, and KSP its p-value.
+	KS  float64 `json:"ks_statistic"`
+	KSP float64 `json:"ks_p"`
+	// Deciles splits the expected distribution into ten equally likely
+	// bins and counts the completed trials falling in each. With censored
+	// trials the later bins expect fewer, since the expectation is
+	// conditioned on finishing within MaxIDs like KS.
+	Deciles  []HuntBin     `json:"deciles"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// HuntBin is one bin of HuntResult.Deciles.
+type HuntBin struct {
+	// Upper is the largest count in the bin; 0 for the last, open bin.
+	Upper    int64   `json:"upper"`
+	Observed int     `json:"observed"`
+	Expected float64 `json:"expected"`
+}
+
+// Hunt runs cfg.Trials collision hunts. Every trial starts from a new
+// generator so that no state, such as a customuid counter, carries over.
+func Hunt(ctx context.Context, cfg HuntConfig) (HuntResult, error) {
+	if cfg.Scheme == "" {
+		cfg.Scheme = "nanoid16"
+	}
+	if cfg.Clock == "" {
+		cfg.Clock = "frozen"
+	}
+	if cfg.Trials <= 0 {
+		cfg.Trials = 100
+	}
+	if cfg.MaxIDs <= 0 {
+		cfg.MaxIDs = 10_000_000
+	}
+	if cfg.Length < 0 {
+		return HuntResult{}, fmt.Errorf("length must be > 0, got %d", cfg.Length)
+	}
+	newGen := func() (tools.Generator, error) {
+		return newGenerator(cfg.Scheme, Config{NodeID: cfg.NodeID, Layout: cfg.Layout, Clock: cfg.Clock})
+	}
+	if cfg.Length > 0 {
+		g, err := tools.Lookup(cfg.Scheme)
+		if err != nil {
+			return HuntResult{}, err
+		}
+		if g.Name() != "nanoid16" {
+			return HuntResult{}, fmt.Errorf("length only applies to nanoid, not %s", g.Name())
+		}
+		newGen = func() (tools.Generator, error) { return tools.NewNanoIdGenerator(cfg.Length), nil }
+	}
+	gen, err := newGen()
+	if err != nil {
+		return HuntResult{}, err
+	}
+	meta := gen.Meta()
+	if meta.EntropyBits <= 0 {
+		return HuntResult{}, fmt.Errorf("%s has no random bits: a single generator never repeats an ID", gen.Name())
+	}
+	// The set takes 16 bytes per slot at a load factor between 3/8 and
+	// 3/4, and briefly twice that while growing.
+	if err := ensureMemory(Config{ApproxBytesPerID: 64, MemGuardMB: cfg.MemGuardMB}, cfg.MaxIDs); err != nil {
+		return HuntResult{}, err
+	}
+
+	res := HuntResult{
+		Scheme:         gen.Name(),
+		SpaceBits:      meta.EntropyBits,
+		IDsPerDraw:     1,
+		SingleTimeUnit: meta.TimeUnit == 0 || strings.HasPrefix(cfg.Clock, "frozen"),
+		Trials:         cfg.Trials,
+	}
+	if meta.CounterBits > 0 {
+		// Counters start at 1, so a counter of c bits covers 2^c - 1 IDs.
+		res.IDsPerDraw = max(int64(1)<<meta.CounterBits-1, 1)
+	}
+
+	start := time.Now()
+	for trial := 0; trial < cfg.Trials; trial++ {
+		if trial > 0 {
+			if gen, err = newGen(); err != nil {
+				return HuntResult{}, err
+			}
+		}
+		n, dup, err := huntTrial(ctx, gen, cfg.MaxIDs)
+		if err != nil {
+			return HuntResult{}, err
+		}
+		if n == 0 {
+			res.Censored++
+		} else {
+			res.Counts = append(res.Counts, n)
+		}
+		if cfg.Verbose {
+			if n == 0 {
+				fmt.Printf("[%s] trial %d: no duplicate in %d IDs\n", res.Scheme, trial+1, cfg.MaxIDs)
+			} else {
+				fmt.Printf("[%s] trial %d: %s repeated after %d IDs\n", res.Scheme, trial+1, dup, n)
+			}
+		}
+	}
+	res.Duration = time.Since(start)
+
+	m := math.Exp2(meta.EntropyBits)
+	draws, drawsSD := firstRepeat(m)
+	per := float64(res.IDsPerDraw)
+	res.Expected = (draws-1)*per + 1
+	res.ExpectedStdDev = drawsSD * per
+	res.SqrtApprox = math.Sqrt(math.Pi*m/2) * per
+	if len(res.Counts) == 0 {
+		return res, nil
+	}
+
+	samples := make([]float64, len(res.Counts))
+	for i, c := range res.Counts {
+		samples[i] = float64(c)
+	}
+	res.Observed = summarize(samples)
+	sorted := slices.Clone(res.Counts)
+	slices.Sort(sorted)
+	res.Min, res.Max = sorted[0], sorted[len(sorted)-1]
+
+	// A trial of n IDs made d = (n-1)/IDsPerDraw + 1 draws, and
+	// P(D <= d) = 1 - P(the first d draws are distinct). Censored trials
+	// are left out, so the distribution is conditioned on n <= MaxIDs.
+	dist := func(n int64) float64 {
+		if n <= 0 {
+			return 0
+		}
+		d := math.Ceil(float64(n-1)/per) + 1
+		return -math.Expm1(logNoCollision(d, m))
+	}
+	total := dist(cfg.MaxIDs)
+	cdf := func(n int64) float64 { return min(dist(n)/total, 1) }
+	res.KS = ksStatistic(sorted, cdf)
+	res.KSP = ksSurvival(res.KS, len(sorted))
+
+	var lower int
+	var prev int64
+	for q := 1; q <= 10; q++ {
+		var bin HuntBin
+		if q < 10 {
+			// Smallest draw count d with P(D <= d) >= q/10, from
+			// d(d-1)/2m = -log(1 - q/10).
+			l := -math.Log1p(-float64(q) / 10)
+			d := math.Ceil((1 + math.Sqrt(1+8*m*l)) / 2)
+			bin.Upper = int64((d-1)*per) + 1
+		}
+		upper, last := len(sorted), cfg.MaxIDs
+		if bin.Upper > 0 {
+			upper, _ = slices.BinarySearch(sorted, bin.Upper+1)
+			last = bin.Upper
+		}
+		bin.Observed = upper - lower
+		bin.Expected = float64(len(sorted)) * (cdf(last) - cdf(prev))
+		lower, prev = upper, last
+		res.Deciles = append(res.Deciles, bin)
+	}
+	return res, nil
+}
+
+// huntTrial generates IDs until one repeats and returns how many were
+// generated, including the repeated one, and the ID itself. It returns 0
+// when maxIDs IDs were generated without a duplicate.
+func huntTrial(ctx context.Context, gen tools.Generator, maxIDs int64) (int64, string, error) {
+	checked, _ := gen.(tools.CheckedGenerator)
+	set := newIDSet()
+	for n := int64(1); n <= maxIDs; n++ {
+		if n%4096 == 0 && ctx.Err() != nil {
+			return 0, "", ctx.Err()
+		}
+		var id string
+		if checked != nil {
+			var err error
+			if id, err = checked.TryGenerate(); err != nil {
+				return 0, "", fmt.Errorf("generator %s: %w", gen.Name(), err)
+			}
+		} else {
+			id = gen.Generate()
+		}
+		if !set.add(id) {
+			return n, id, nil
+		}
+	}
+	return 0, "", nil
+}
+
+// idSet is an open-addressing set of IDs packed into 16 bytes each: IDs of
+// up to 16 bytes are stored as they are (IDs never contain NUL bytes, so
+// the zero padding is unambiguous), longer ones as a 128-bit hash whose
+// false positives are negligible at any size that fits in memory.
+type idSet struct {
+	slots [][2]uint64
+	count int
+	zero  bool // the all-zero key, which marks empty slots
+	seeds [2]maphash.Seed
+}
+
+func newIDSet() *idSet {
+	return &idSet{
+		slots: make([][2]uint64, 1024),
+		seeds: [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+	}
+}
+
+// add inserts id and reports whether it was absent.
+func (s *idSet) add(id string) bool {
+	key := s.key(id)
+	if key == ([2]uint64{}) {
+		added := !s.zero
+		s.zero = true
+		return added
+	}
+	if (s.count+1)*4 > len(s.slots)*3 {
+		s.grow()
+	}
+	if !s.insert(key) {
+		return false
+	}
+	s.count++
+	return true
+}
+
+// insert places key in its probe sequence unless it is already present.
+func (s *idSet) insert(key [2]uint64) bool {
+	mask := uint64(len(s.slots) - 1)
+	for i := mix(key) & mask; ; i = (i + 1) & mask {
+		switch s.slots[i] {
+		case key:
+			return false
+		case [2]uint64{}:
+			s.slots[i] = key
+			return true
+		}
+	}
+}
+
+// grow doubles the table.
+func (s *idSet) grow() {
+	old := s.slots
+	s.slots = make([][2]uint64, 2*len(old))
+	for _, key := range old {
+		if key != ([2]uint64{}) {
+			s.insert(key)
+		}
+	}
+}
+
+func (s *idSet) key(id string) [2]uint64 {
+	if len(id) > 16 {
+		return [2]uint64{maphash.String(s.seeds[0], id), maphash.String(s.seeds[1], id)}
+	}
+	var b [16]byte
+	copy(b[:], id)
+	return [2]uint64{binary.LittleEndian.Uint64(b[:8]), binary.LittleEndian.Uint64(b[8:])}
+}
+
+// mix spreads the bits of key over a 64-bit slot hash (the splitmix64
+// finalizer), so that packed IDs sharing a prefix do not cluster.
+func mix(key [2]uint64) uint64 {
+	x := key[0] ^ bits.RotateLeft64(key[1], 31)
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// firstRepeat returns the mean and standard deviation of the number of
+// uniform draws from m values up to and including the first repeat,
+// summing P(D > d) exactly for spaces small enough to hunt in and using
+// the asymptotic expansions beyond.
+func firstRepeat(m float64) (mean, stddev float64) {
+	if m <= 1e12 {
+		// E[D] = Σ P(D > d) and E[D²] = Σ (2d+1)·P(D > d) over d >= 0,
+		// where P(D > d) = Π (1 - i/m) for i < d.
+		var s0, s1 float64
+		p := 1.0
+		for d := 0.0; d <= m && p > 1e-18; d++ {
+			s0 += p
+			s1 += (2*d + 1) * p
+			p *= 1 - d/m
+		}
+		return s0, math.Sqrt(max(s1-s0*s0, 0))
+	}
+	mean = 1 + math.Sqrt(math.Pi*m/2) - 1.0/3 + math.Sqrt(math.Pi/(2*m))/12
+	return mean, math.Sqrt((2 - math.Pi/2) * m)
+}
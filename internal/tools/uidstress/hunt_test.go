@@ -0,0 +1,94 @@
+package uidstress
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestIDSet checks insertion and membership for packed and hashed IDs,
+// including IDs of exactly 16 bytes and the all-zero key.
+func TestIDSet(t *testing.T) {
+	s := newIDSet()
+	ids := []string{
+		"",                  // packs to the all-zero key
+		"a",                 // short
+		"abcdefghijklmno",   // 15 bytes
+		"abcdefghijklmnop",  // exactly 16 bytes, the longest packed ID
+		"abcdefghijklmnoq",  // differs from the previous only in byte 16
+		"abcdefghijklmnopq", // 17 bytes, hashed
+		"abcdefghijklmnopr",
+		strings.Repeat("z", 36),
+	}
+	for _, id := range ids {
+		if !s.add(id) {
+			t.Errorf("add(%q) on first insert = false", id)
+		}
+	}
+	for _, id := range ids {
+		if s.add(id) {
+			t.Errorf("add(%q) on second insert = true", id)
+		}
+	}
+	// The zero key lives outside the table.
+	if s.count != len(ids)-1 || !s.zero {
+		t.Errorf("count = %d, zero = %t, want %d and true", s.count, s.zero, len(ids)-1)
+	}
+}
+
+// TestIDSetGrow checks that every ID is still found after the table has
+// doubled several times, and that the load factor stays below 3/4.
+func TestIDSetGrow(t *testing.T) {
+	const n = 50_000
+	s := newIDSet()
+	for i := range n {
+		// Shared prefixes and lengths on both sides of 16 bytes.
+		id := fmt.Sprintf("id-%0*d", 10+i%10, i)
+		if !s.add(id) {
+			t.Fatalf("add(%q) of a new ID = false", id)
+		}
+	}
+	if len(s.slots) <= 1024 || len(s.slots)&(len(s.slots)-1) != 0 {
+		t.Errorf("table has %d slots, want a power of two above 1024", len(s.slots))
+	}
+	if s.count != n || s.count*4 > len(s.slots)*3 {
+		t.Errorf("%d IDs in %d slots, want %d below a 3/4 load", s.count, len(s.slots), n)
+	}
+	for i := range n {
+		if id := fmt.Sprintf("id-%0*d", 10+i%10, i); s.add(id) {
+			t.Fatalf("add(%q) after growing = true, want it found", id)
+		}
+	}
+}
+
+// TestFirstRepeat checks the number of draws up to the first repeat
+// against exact small cases, the classic birthday problem and the
+// asymptotic mean sqrt(πm/2) + 2/3 with stddev sqrt((2 - π/2)m).
+func TestFirstRepeat(t *testing.T) {
+	exact := []struct {
+		m, mean, stddev float64
+	}{
+		{1, 2, 0},
+		{2, 2.5, 0.5},
+		{365, 24.6166, math.NaN()},
+	}
+	for _, tt := range exact {
+		mean, stddev := firstRepeat(tt.m)
+		if math.Abs(mean-tt.mean) > 1e-4 || (!math.IsNaN(tt.stddev) && math.Abs(stddev-tt.stddev) > 1e-9) {
+			t.Errorf("firstRepeat(%g) = %g ± %g, want %g ± %g", tt.m, mean, stddev, tt.mean, tt.stddev)
+		}
+	}
+
+	for _, m := range []float64{1e4, 1e6, 1e9, 1e12, 1e13, math.Exp2(64)} {
+		mean, stddev := firstRepeat(m)
+		wantMean := math.Sqrt(math.Pi*m/2) + 2.0/3
+		wantStdDev := math.Sqrt((2 - math.Pi/2) * m)
+		if math.Abs(mean-wantMean) > 1e-3*wantMean {
+			t.Errorf("firstRepeat(%g) mean = %g, want about %g", m, mean, wantMean)
+		}
+		if math.Abs(stddev-wantStdDev) > 1e-2*wantStdDev {
+			t.Errorf("firstRepeat(%g) stddev = %g, want about %g", m, stddev, wantStdDev)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package uidstress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// MergeConfig controls a merge of existing runs, e.g. runs made on
+// different machines and copied locally.
+type MergeConfig struct {
+	// Manifests lists the runs to merge, as manifest.json paths or the run
+	// directories holding them. Each run must have been kept (-keep).
+	Manifests []string `json:"manifests"`
+	// ReportDir receives merge-<scheme>-duplicates.ndjson for every scheme
+	// with collisions (default: the current directory).
+	ReportDir   string `json:"report_dir"`
+	Verbose     bool   `json:"verbose"`
+	LogInterval int64  `json:"log_interval"`
+}
+
+// Merge verifies the chunk hashes of every run and k-way merges the runs
+// of each scheme together, as if all their generators wrote to one table.
+// It returns one result per scheme, in the order the schemes first appear.
+func Merge(ctx context.Context, cfg MergeConfig) ([]Result, error) {
+	if len(cfg.Manifests) == 0 {
+		return nil, fmt.Errorf("no manifests to merge")
+	}
+	if cfg.ReportDir == "" {
+		cfg.ReportDir = "."
+	}
+	if cfg.LogInterval <= 0 {
+		cfg.LogInterval = 1_000_000
+	}
+
+	var (
+		order   []string
+		runs    = make(map[string][]*manifest)
+		sources = make(map[string][]string)
+		seen    = make(map[string]bool)
+	)
+	for _, path := range cfg.Manifests {
+		dir := path
+		if filepath.Base(path) == "manifest.json" {
+			dir = filepath.Dir(path)
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if seen[abs] {
+			return nil, fmt.Errorf("run %s given more than once", dir)
+		}
+		seen[abs] = true
+
+		man, err := loadManifest(dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyChunks(man); err != nil {
+			return nil, err
+		}
+		if cfg.Verbose {
+			fmt.Printf("[merge] %s: %s, %d chunks verified\n", dir, man.Scheme, len(man.Chunks))
+		}
+		if _, ok := runs[man.Scheme]; !ok {
+			order = append(order, man.Scheme)
+		}
+		runs[man.Scheme] = append(runs[man.Scheme], man)
+		sources[man.Scheme] = append(sources[man.Scheme], filepath.Clean(dir))
+	}
+
+	if err := os.MkdirAll(cfg.ReportDir, 0o755); err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(order))
+	for _, scheme := range order {
+		start := time.Now()
+		combined, err := combineManifests(runs[scheme], sources[scheme])
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			decoder  tools.TimeDecoder
+			sortable bool
+		)
+		// combineManifests made sure every run used the same layout. Truncated
+		// IDs carry no timestamp, so they are neither decoded nor sortable.
+		if gen, err := newGenerator(scheme, Config{Layout: combined.Layout}); err == nil && combined.TruncateBits == 0 {
+			decoder, _ = gen.(tools.TimeDecoder)
+			sortable = gen.Meta().Sortable
+		}
+		reportPath := filepath.Join(cfg.ReportDir, "merge-"+scheme+"-duplicates.ndjson")
+		// A report left by an earlier merge would otherwise survive a clean one.
+		if err := os.Remove(reportPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		res, err := mergeRun(ctx, combined, reportPath, decoder, Config{Verbose: cfg.Verbose, LogInterval: cfg.LogInterval})
+		if err != nil {
+			return nil, err
+		}
+		res.Sortable = sortable
+		res.Duration = time.Since(start)
+		results = append(results, res)
+	}
+	return results, nil
+}
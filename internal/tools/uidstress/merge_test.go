@@ -0,0 +1,95 @@
+package uidstress
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMerge merges two kept runs from disk: c is in both, e twice in the
+// second one.
+func TestMerge(t *testing.T) {
+	first := runScripted(t, []string{"a", "b", "c", "d"}, 2)
+	second := runScripted(t, []string{"c", "e", "e", "f"}, 2)
+	reportDir := t.TempDir()
+	results, err := Merge(context.Background(), MergeConfig{
+		Manifests: []string{first.OutputDir, second.ManifestPath},
+		ReportDir: reportDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	res := results[0]
+	if res.Scheme != "testseq" || res.Sources != 2 || res.Generated != 8 || res.Unique != 6 ||
+		res.TotalDuplicates != 2 || res.CrossSourceDuplicates != 1 {
+		t.Errorf("scheme %s, sources %d, generated %d, unique %d, duplicates %d, cross-source %d; want testseq, 2, 8, 6, 2, 1",
+			res.Scheme, res.Sources, res.Generated, res.Unique, res.TotalDuplicates, res.CrossSourceDuplicates)
+	}
+	reportPath := filepath.Join(reportDir, "merge-testseq-duplicates.ndjson")
+	if res.DuplicatesReport != reportPath {
+		t.Errorf("report %q, want %q", res.DuplicatesReport, reportPath)
+	}
+	records := readDuplicates(t, reportPath)
+	if len(records) != 2 || records["c"].Count != 2 || records["e"].Count != 2 {
+		t.Errorf("report %+v, want c and e twice each", records)
+	}
+	for _, occ := range records["c"].Occurrences {
+		if occ.Source != first.OutputDir && occ.Source != second.OutputDir {
+			t.Errorf("c found in %q, want one of the run directories", occ.Source)
+		}
+	}
+
+	if _, err := Merge(context.Background(), MergeConfig{
+		Manifests: []string{first.OutputDir, first.ManifestPath},
+		ReportDir: reportDir,
+	}); err == nil {
+		t.Error("Merge accepted the same run twice")
+	}
+}
+
+// TestMergeRejects checks that Merge refuses runs made with a different
+// clock and chunks that no longer match their manifest hash.
+func TestMergeRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, dir string)
+		want   string
+	}{
+		{"clock", func(t *testing.T, dir string) {
+			man, err := loadManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			man.Clock = "frozen"
+			if err := saveManifest(dir, man); err != nil {
+				t.Fatal(err)
+			}
+		}, "clock"},
+		{"chunk", func(t *testing.T, dir string) {
+			man, err := loadManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(man.Chunks[1].Path, []byte("c\nz\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, "hash"},
+	}
+	for _, tt := range tests {
+		first := runScripted(t, []string{"a", "b", "c", "d"}, 2)
+		second := runScripted(t, []string{"c", "e", "e", "f"}, 2)
+		tt.tamper(t, second.OutputDir)
+		_, err := Merge(context.Background(), MergeConfig{
+			Manifests: []string{first.OutputDir, second.OutputDir},
+			ReportDir: t.TempDir(),
+		})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Merge = %v, want an error mentioning %q", tt.name, err, tt.want)
+		}
+	}
+}
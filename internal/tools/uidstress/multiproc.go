@@ -0,0 +1,269 @@
+package uidstress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// MultiProcConfig controls a multi-process collision run: for each scheme,
+// Procs child processes start in the same second, each runs the stress test
+// into its own directory, and the outputs of all children are then merged
+// to count collisions between processes.
+type MultiProcConfig struct {
+	// Config holds the per-process settings. Scale is the number of IDs
+	// each process generates per scheme; Duration and Rate likewise apply
+	// to each process. ResumeDir and StartAt are ignored.
+	Config
+	// Procs is the number of child processes.
+	Procs int `json:"procs"`
+	// DistinctNodeIDs gives child i the node ID NodeID+i so schemes that
+	// embed one (snowflake, customuid) can tell the processes apart.
+	// Without it every child uses NodeID, like copies of one deployment.
+	DistinctNodeIDs bool `json:"distinct_node_ids"`
+	// Executable is the uidstress binary the children run (default: the
+	// current executable).
+	Executable string `json:"executable,omitempty"`
+	// StartDelay leaves time for the children to start before the common
+	// start second (default 2s).
+	StartDelay time.Duration `json:"start_delay_ns"`
+}
+
+// MultiProc runs the children and returns one merged result per scheme.
+// Every scheme gets its own launch of the children so that each starts
+// generating at a common second rather than after the previous scheme.
+// The children's output is kept in <scheme>/proc-NNN/output.log under the
+// run directory, which is removed on success unless KeepTempData is set.
+func MultiProc(ctx context.Context, cfg MultiProcConfig) (_ []Result, err error) {
+	if cfg.Procs <= 0 {
+		return nil, fmt.Errorf("procs must be > 0")
+	}
+	if cfg.Scale <= 0 && cfg.Duration <= 0 {
+		return nil, fmt.Errorf("scale must be > 0 unless a duration is given")
+	}
+	if len(cfg.Schemes) == 0 {
+		cfg.Schemes = []string{"nanoid16", "ulid", "ksuid"}
+	}
+	schemes, err := resolveSchemes(cfg.Schemes)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Schemes = schemes
+	if cfg.Executable == "" {
+		if cfg.Executable, err = os.Executable(); err != nil {
+			return nil, fmt.Errorf("locate executable: %w", err)
+		}
+	}
+	if cfg.StartDelay <= 0 {
+		cfg.StartDelay = 2 * time.Second
+	}
+
+	runDir, err := createRunDir(cfg.TempDir, "multiproc")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		switch {
+		case err == nil && !cfg.KeepTempData:
+			os.RemoveAll(runDir)
+		case err != nil:
+			err = fmt.Errorf("%w (child output kept in %s)", err, runDir)
+		}
+	}()
+
+	results := make([]Result, 0, len(cfg.Schemes))
+	for _, scheme := range cfg.Schemes {
+		r, err := multiProcScheme(ctx, cfg, runDir, scheme)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// multiProcScheme launches the children for one scheme under
+// runDir/<scheme>, lined up on the start of the same second, and merges
+// their runs. The result's Duration covers both, from the launch of the
+// children to the end of the merge.
+func multiProcScheme(ctx context.Context, cfg MultiProcConfig, runDir, scheme string) (Result, error) {
+	start := time.Now()
+	cfg.Schemes = []string{scheme}
+	startAt := start.Add(cfg.StartDelay).Truncate(time.Second).Add(time.Second)
+	if cfg.Verbose {
+		fmt.Printf("[multiproc] starting %d %s processes at %s\n", cfg.Procs, scheme, startAt.Format(time.RFC3339))
+	}
+	procDirs, err := runChildren(ctx, cfg, filepath.Join(runDir, scheme), startAt)
+	if err != nil {
+		return Result{}, err
+	}
+
+	mans := make([]*manifest, 0, len(procDirs))
+	for _, dir := range procDirs {
+		man, err := findManifest(dir, scheme)
+		if err != nil {
+			return Result{}, err
+		}
+		if err := verifyChunks(man); err != nil {
+			return Result{}, err
+		}
+		mans = append(mans, man)
+	}
+	sources := make([]string, len(procDirs))
+	for i, dir := range procDirs {
+		sources[i] = filepath.Base(dir)
+	}
+	combined, err := combineManifests(mans, sources)
+	if err != nil {
+		return Result{}, err
+	}
+
+	gen, err := newGenerator(scheme, cfg.Config)
+	if err != nil {
+		return Result{}, err
+	}
+	decoder, _ := gen.(tools.TimeDecoder)
+	if cfg.TruncateBits > 0 {
+		decoder = nil
+	}
+	reportPath := filepath.Clean(runDir) + "-" + scheme + "-duplicates.ndjson"
+	r, err := mergeRun(ctx, combined, reportPath, decoder, cfg.Config)
+	if err != nil {
+		return Result{}, err
+	}
+	r.Sortable = gen.Meta().Sortable && cfg.TruncateBits == 0
+	if cfg.KeepTempData {
+		r.OutputDir = runDir
+	}
+	r.Duration = time.Since(start)
+	return r, nil
+}
+
+// runChildren starts one child per process and waits for all of them. It
+// returns the directory of each child in process order. If any child cannot
+// be started, the ones already running are killed.
+func runChildren(ctx context.Context, cfg MultiProcConfig, runDir string, startAt time.Time) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dirs := make([]string, cfg.Procs)
+	errs := make([]error, cfg.Procs)
+	var wg sync.WaitGroup
+	abort := func(err error) ([]string, error) {
+		cancel()
+		wg.Wait()
+		return nil, err
+	}
+	for i := range dirs {
+		dirs[i] = filepath.Join(runDir, fmt.Sprintf("proc-%03d", i))
+		if err := os.MkdirAll(dirs[i], 0o755); err != nil {
+			return abort(err)
+		}
+		logFile, err := os.Create(filepath.Join(dirs[i], "output.log"))
+		if err != nil {
+			return abort(err)
+		}
+
+		nodeID := cfg.NodeID
+		if cfg.DistinctNodeIDs {
+			nodeID += int64(i)
+		}
+		cmd := exec.CommandContext(ctx, cfg.Executable, childArgs(cfg, dirs[i], nodeID, startAt)...)
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		if err := cmd.Start(); err != nil {
+			logFile.Close()
+			return abort(fmt.Errorf("start process %d: %w", i, err))
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer logFile.Close()
+			if err := cmd.Wait(); err != nil {
+				errs[i] = fmt.Errorf("process %d: %w (see %s)", i, err, logFile.Name())
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return dirs, errors.Join(errs...)
+}
+
+// childArgs builds the stress-test command line for one child, which
+// writes its runs under dir and keeps them for merging.
+func childArgs(cfg MultiProcConfig, dir string, nodeID int64, startAt time.Time) []string {
+	args := []string{
+		"-schemes", strings.Join(cfg.Schemes, ","),
+		"-scale", strconv.FormatInt(cfg.Scale, 10),
+		"-tempdir", dir,
+		"-keep",
+		"-start-at", startAt.Format(time.RFC3339Nano),
+		"-node-id", strconv.FormatInt(nodeID, 10),
+	}
+	if cfg.ChunkSize > 0 {
+		args = append(args, "-chunk", strconv.FormatInt(cfg.ChunkSize, 10))
+	}
+	if cfg.Workers > 0 {
+		args = append(args, "-workers", strconv.Itoa(cfg.Workers))
+	}
+	if cfg.LogInterval > 0 {
+		args = append(args, "-log-interval", strconv.FormatInt(cfg.LogInterval, 10))
+	}
+	if cfg.MemGuardMB > 0 {
+		args = append(args, "-mem-guard", strconv.FormatFloat(cfg.MemGuardMB, 'f', -1, 64))
+	}
+	if cfg.ApproxBytesPerID > 0 {
+		args = append(args, "-bytes-per-id", strconv.FormatInt(cfg.ApproxBytesPerID, 10))
+	}
+	if cfg.DiskSafetyFactor > 0 {
+		args = append(args, "-disk-factor", strconv.FormatFloat(cfg.DiskSafetyFactor, 'f', -1, 64))
+	}
+	if cfg.Layout != "" {
+		args = append(args, "-layout", cfg.Layout)
+	}
+	if cfg.Clock != "" {
+		args = append(args, "-clock", cfg.Clock)
+	}
+	if cfg.Duration > 0 {
+		args = append(args, "-duration", cfg.Duration.String())
+	}
+	if cfg.Rate > 0 {
+		args = append(args, "-rate", strconv.FormatFloat(cfg.Rate, 'f', -1, 64))
+	}
+	if cfg.TrackOrder {
+		args = append(args, "-order")
+	}
+	if cfg.TruncateBits > 0 {
+		args = append(args, "-truncate-bits", strconv.Itoa(cfg.TruncateBits))
+	}
+	if cfg.LatencySample > 0 {
+		args = append(args, "-latency", strconv.FormatInt(cfg.LatencySample, 10))
+	}
+	if cfg.Verbose {
+		args = append(args, "-verbose")
+	}
+	return args
+}
+
+// findManifest loads the run of scheme a child left under dir.
+func findManifest(dir, scheme string) (*manifest, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "uidstress-"+scheme+"-*", "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("%s: found %d %s runs, want 1", dir, len(matches), scheme)
+	}
+	return loadManifest(filepath.Dir(matches[0]))
+}
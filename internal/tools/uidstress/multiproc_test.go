@@ -0,0 +1,104 @@
+package uidstress
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestChildArgs checks that every per-process setting reaches the child's
+// command line and that unset ones are left out.
+func TestChildArgs(t *testing.T) {
+	startAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := MultiProcConfig{Config: Config{
+		Schemes:      []string{"ulid", "customuid"},
+		Scale:        1000,
+		Workers:      2,
+		Layout:       "counter=0,random=40",
+		Clock:        "frozen",
+		Duration:     3 * time.Second,
+		Rate:         250.5,
+		TrackOrder:   true,
+		TruncateBits: 20,
+	}}
+	args := childArgs(cfg, "/tmp/proc-007", 7, startAt)
+	want := map[string]string{
+		"-schemes":       "ulid,customuid",
+		"-scale":         "1000",
+		"-tempdir":       "/tmp/proc-007",
+		"-start-at":      "2026-03-01T12:00:00Z",
+		"-node-id":       "7",
+		"-workers":       "2",
+		"-layout":        "counter=0,random=40",
+		"-clock":         "frozen",
+		"-duration":      "3s",
+		"-rate":          "250.5",
+		"-truncate-bits": "20",
+	}
+	for name, value := range want {
+		i := slices.Index(args, name)
+		if i < 0 || i+1 >= len(args) || args[i+1] != value {
+			t.Errorf("childArgs: %s missing or not %q in %q", name, value, args)
+		}
+	}
+	for _, name := range []string{"-keep", "-order"} {
+		if !slices.Contains(args, name) {
+			t.Errorf("childArgs: %s missing in %q", name, args)
+		}
+	}
+	for _, name := range []string{"-chunk", "-latency", "-verbose", "-mem-guard"} {
+		if slices.Contains(args, name) {
+			t.Errorf("childArgs: unset %s present in %q", name, args)
+		}
+	}
+}
+
+// TestCombineManifestsCrossSource merges two scripted runs: c appears in
+// both, e twice in the second one only.
+func TestCombineManifestsCrossSource(t *testing.T) {
+	first := runScripted(t, []string{"a", "b", "c", "d"}, 2)
+	second := runScripted(t, []string{"c", "e", "e", "f"}, 2)
+	var mans []*manifest
+	for _, dir := range []string{first.OutputDir, second.OutputDir} {
+		man, err := loadManifest(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mans = append(mans, man)
+	}
+	combined, err := combineManifests(mans, []string{"proc-000", "proc-001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportPath := filepath.Join(t.TempDir(), "duplicates.ndjson")
+	res, err := mergeRun(context.Background(), combined, reportPath, nil, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Sources != 2 || res.Generated != 8 || res.Unique != 6 || res.TotalDuplicates != 2 || res.CrossSourceDuplicates != 1 {
+		t.Errorf("sources %d, generated %d, unique %d, duplicates %d, cross-source %d; want 2, 8, 6, 2, 1",
+			res.Sources, res.Generated, res.Unique, res.TotalDuplicates, res.CrossSourceDuplicates)
+	}
+
+	records := readDuplicates(t, reportPath)
+	var sources []string
+	for _, occ := range records["c"].Occurrences {
+		sources = append(sources, occ.Source)
+	}
+	slices.Sort(sources)
+	if !slices.Equal(sources, []string{"proc-000", "proc-001"}) {
+		t.Errorf("c found in %v, want proc-000 and proc-001", sources)
+	}
+	for _, occ := range records["e"].Occurrences {
+		if occ.Source != "proc-001" {
+			t.Errorf("e found in %s, want only proc-001", occ.Source)
+		}
+	}
+
+	mans[1].Clock = "frozen"
+	if _, err := combineManifests(mans, []string{"proc-000", "proc-001"}); err == nil {
+		t.Error("combineManifests accepted runs with different clocks")
+	}
+}
@@ -0,0 +1,252 @@
+package uidstress
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// QualityConfig controls a character distribution test: a sample of IDs of
+// each scheme is generated and every character position is tested for
+// uniformity over the scheme's charset.
+type QualityConfig struct {
+	// Schemes lists the schemes to test; empty or "all" means every
+	// registered scheme.
+	Schemes []string `json:"schemes"`
+	// Samples is the number of IDs generated per scheme.
+	Samples int64 `json:"samples"`
+	// Alpha is the family-wise significance level of each scheme: a
+	// position is flagged when its p-value is below Alpha divided by the
+	// number of positions tested (Bonferroni).
+	Alpha  float64 `json:"alpha"`
+	NodeID int64   `json:"node_id,omitempty"`
+	Layout string  `json:"layout,omitempty"`
+}
+
+// PositionQuality is the distribution of one character position.
+type PositionQuality struct {
+	Position int `json:"position"`
+	// Samples counts the IDs long enough to have this position.
+	Samples int64 `json:"samples"`
+	// Counts maps each character seen at this position to its frequency.
+	Counts map[string]int64 `json:"counts"`
+	// Fixed is set when a single character was seen (separators, version
+	// digits); fixed positions are not tested.
+	Fixed bool `json:"fixed"`
+	// Alphabet is the number of characters the position was tested
+	// against.
+	Alphabet int `json:"alphabet"`
+	// Foreign counts characters outside the scheme's charset.
+	Foreign   int64   `json:"foreign"`
+	ChiSquare float64 `json:"chi_square"`
+	DF        int     `json:"df"`
+	P         float64 `json:"p"`
+	// Entropy is the Shannon entropy of the observed frequencies in bits;
+	// MaxEntropy is log2(Alphabet), reached by a uniform position.
+	Entropy    float64 `json:"entropy_bits"`
+	MaxEntropy float64 `json:"max_entropy_bits"`
+	// Biased is set when the position fails the uniformity test or holds
+	// foreign characters.
+	Biased bool `json:"biased"`
+	// TooUniform is set when the frequencies are closer to uniform than
+	// random sampling plausibly allows (p-value above 1 - threshold), as
+	// with sequential counters.
+	TooUniform bool `json:"too_uniform,omitempty"`
+}
+
+// QualityResult holds the per-position analysis of one scheme.
+type QualityResult struct {
+	Scheme  string `json:"scheme"`
+	Charset string `json:"charset"`
+	Samples int64  `json:"samples"`
+	// Threshold is the per-position p-value below which a position is
+	// flagged.
+	Threshold float64 `json:"threshold"`
+	// EntropyBits sums the entropy of every position, an upper bound on
+	// the entropy of the whole ID since positions may be correlated;
+	// ClaimedBits is the scheme's Meta.EntropyBits for comparison.
+	EntropyBits float64           `json:"entropy_bits"`
+	ClaimedBits float64           `json:"claimed_bits"`
+	Biased      int               `json:"biased_positions"`
+	Positions   []PositionQuality `json:"positions"`
+}
+
+// Quality samples each scheme and tests every position for uniformity.
+//
+// Characters of the charset that only ever appear as the single character
+// of a fixed position (the '-' of UUIDs) are left out of the alphabet of
+// the other positions, so separators do not make every position look
+// biased. Positions that are not meant to be random, such as timestamp
+// prefixes or UUID variant bits, are expected to be flagged.
+func Quality(ctx context.Context, cfg QualityConfig) ([]QualityResult, error) {
+	if len(cfg.Schemes) == 0 {
+		cfg.Schemes = []string{"all"}
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 1_000_000
+	}
+	if cfg.Alpha <= 0 || cfg.Alpha >= 1 {
+		cfg.Alpha = 0.01
+	}
+	schemes, err := resolveSchemes(cfg.Schemes)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]QualityResult, 0, len(schemes))
+	for _, scheme := range schemes {
+		gen, err := newGenerator(scheme, Config{NodeID: cfg.NodeID, Layout: cfg.Layout})
+		if err != nil {
+			return nil, err
+		}
+		var counts [][256]int64
+		for i := int64(0); i < cfg.Samples; i++ {
+			if i%65536 == 0 && ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			id := gen.Generate()
+			for len(counts) < len(id) {
+				counts = append(counts, [256]int64{})
+			}
+			for p := 0; p < len(id); p++ {
+				counts[p][id[p]]++
+			}
+		}
+		meta := gen.Meta()
+		res := analyzeQuality(counts, meta.Charset, cfg.Alpha)
+		res.Scheme = gen.Name()
+		res.Samples = cfg.Samples
+		res.ClaimedBits = meta.EntropyBits
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// analyzeQuality tests the per-position byte counts against charset.
+func analyzeQuality(counts [][256]int64, charset string, alpha float64) QualityResult {
+	res := QualityResult{Charset: charset}
+	var inCharset [256]bool
+	for i := 0; i < len(charset); i++ {
+		inCharset[charset[i]] = true
+	}
+
+	// Characters that make up a fixed position and never appear anywhere
+	// else are separators, not part of the random alphabet.
+	var fixedOnly [256]bool
+	for _, c := range counts {
+		if ch, ok := singleChar(c); ok {
+			fixedOnly[ch] = true
+		}
+	}
+	for _, c := range counts {
+		if _, ok := singleChar(c); ok {
+			continue
+		}
+		for ch, n := range c {
+			if n > 0 {
+				fixedOnly[ch] = false
+			}
+		}
+	}
+
+	tested := 0
+	for p, c := range counts {
+		pq := PositionQuality{Position: p, Counts: make(map[string]int64)}
+		for ch, n := range c {
+			if n == 0 {
+				continue
+			}
+			pq.Samples += n
+			pq.Counts[string(rune(ch))] = n
+			if !inCharset[ch] {
+				pq.Foreign += n
+			}
+		}
+		if _, ok := singleChar(c); ok {
+			pq.Fixed = true
+			pq.Biased = pq.Foreign > 0
+		} else {
+			tested++
+			for i := 0; i < len(charset); i++ {
+				if !fixedOnly[charset[i]] || c[charset[i]] > 0 {
+					pq.Alphabet++
+				}
+			}
+			expected := float64(pq.Samples-pq.Foreign) / float64(pq.Alphabet)
+			for i := 0; i < len(charset); i++ {
+				ch := charset[i]
+				if fixedOnly[ch] && c[ch] == 0 {
+					continue
+				}
+				d := float64(c[ch]) - expected
+				pq.ChiSquare += d * d / expected
+			}
+			pq.DF = pq.Alphabet - 1
+			pq.P = chiSquareSurvival(pq.ChiSquare, pq.DF)
+			pq.MaxEntropy = math.Log2(float64(pq.Alphabet))
+		}
+		for _, n := range c {
+			if n > 0 {
+				f := float64(n) / float64(pq.Samples)
+				pq.Entropy -= f * math.Log2(f)
+			}
+		}
+		res.EntropyBits += pq.Entropy
+		res.Positions = append(res.Positions, pq)
+	}
+
+	if tested > 0 {
+		res.Threshold = alpha / float64(tested)
+	}
+	for i := range res.Positions {
+		pq := &res.Positions[i]
+		if !pq.Fixed && (pq.P < res.Threshold || pq.Foreign > 0) {
+			pq.Biased = true
+		}
+		pq.TooUniform = !pq.Fixed && !pq.Biased && pq.P > 1-res.Threshold
+		if pq.Biased {
+			res.Biased++
+		}
+	}
+	return res
+}
+
+// singleChar reports whether c counts exactly one distinct character.
+func singleChar(c [256]int64) (byte, bool) {
+	found, ch := false, byte(0)
+	for i, n := range c {
+		if n == 0 {
+			continue
+		}
+		if found {
+			return 0, false
+		}
+		found, ch = true, byte(i)
+	}
+	return ch, found
+}
+
+// Summary lists the flagged positions of r on one line.
+func (r QualityResult) Summary() string {
+	var biased, uniform []string
+	for _, pq := range r.Positions {
+		switch {
+		case pq.Biased:
+			biased = append(biased, fmt.Sprint(pq.Position))
+		case pq.TooUniform:
+			uniform = append(uniform, fmt.Sprint(pq.Position))
+		}
+	}
+	var parts []string
+	if len(biased) > 0 {
+		parts = append(parts, "biased positions: "+strings.Join(biased, ","))
+	}
+	if len(uniform) > 0 {
+		parts = append(parts, "too uniform: "+strings.Join(uniform, ","))
+	}
+	if len(parts) == 0 {
+		return "no biased positions"
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,98 @@
+package uidstress
+
+import (
+	"math"
+	"testing"
+)
+
+// qualityCounts builds per-position byte counts from one map per position.
+func qualityCounts(positions ...map[byte]int64) [][256]int64 {
+	counts := make([][256]int64, len(positions))
+	for p, m := range positions {
+		for ch, n := range m {
+			counts[p][ch] = n
+		}
+	}
+	return counts
+}
+
+// TestAnalyzeQuality checks each flag on scripted counts of 1000 IDs over
+// the charset "abcd-", where '-' is a separator.
+func TestAnalyzeQuality(t *testing.T) {
+	counts := qualityCounts(
+		// 0: the separator, fixed and left out of the other alphabets.
+		map[byte]int64{'-': 1000},
+		// 1: exactly uniform, chi-square 0 and p = 1.
+		map[byte]int64{'a': 250, 'b': 250, 'c': 250, 'd': 250},
+		// 2: chi-square (150² + 3·50²)/250 = 120.
+		map[byte]int64{'a': 400, 'b': 200, 'c': 200, 'd': 200},
+		// 3: chi-square 2·40²/250 = 12.8, p ≈ 0.005: below alpha but above
+		// alpha divided by the 4 tested positions.
+		map[byte]int64{'a': 290, 'b': 210, 'c': 250, 'd': 250},
+		// 4: fixed on a character outside the charset.
+		map[byte]int64{'x': 1000},
+		// 5: nearly uniform but with one foreign character; the expected count
+		// of each charset character is 999/4.
+		map[byte]int64{'a': 249, 'b': 250, 'c': 250, 'd': 250, 'Z': 1},
+	)
+	res := analyzeQuality(counts, "abcd-", 0.01)
+
+	if res.Threshold != 0.01/4 {
+		t.Errorf("threshold %g, want 0.01/4 for 4 tested positions", res.Threshold)
+	}
+	want := []struct {
+		fixed, biased, tooUniform bool
+		alphabet                  int
+		foreign                   int64
+		chiSquare                 float64
+	}{
+		{fixed: true},
+		{tooUniform: true, alphabet: 4},
+		{biased: true, alphabet: 4, chiSquare: 120},
+		{alphabet: 4, chiSquare: 12.8},
+		{fixed: true, biased: true, foreign: 1000},
+		{biased: true, alphabet: 4, foreign: 1, chiSquare: (0.75*0.75 + 3*0.25*0.25) / 249.75},
+	}
+	for p, w := range want {
+		pq := res.Positions[p]
+		if pq.Fixed != w.fixed || pq.Biased != w.biased || pq.TooUniform != w.tooUniform ||
+			pq.Alphabet != w.alphabet || pq.Foreign != w.foreign || math.Abs(pq.ChiSquare-w.chiSquare) > 1e-9 {
+			t.Errorf("position %d: fixed %t, biased %t, too uniform %t, alphabet %d, foreign %d, chi-square %g; want %t, %t, %t, %d, %d, %g",
+				p, pq.Fixed, pq.Biased, pq.TooUniform, pq.Alphabet, pq.Foreign, pq.ChiSquare,
+				w.fixed, w.biased, w.tooUniform, w.alphabet, w.foreign, w.chiSquare)
+		}
+		if pq.Samples != 1000 {
+			t.Errorf("position %d: %d samples, want 1000", p, pq.Samples)
+		}
+	}
+	if p := res.Positions[3].P; p <= res.Threshold || p >= 0.01 {
+		t.Errorf("position 3: p = %g, want between %g and 0.01", p, res.Threshold)
+	}
+	if res.Biased != 3 {
+		t.Errorf("%d biased positions, want 3", res.Biased)
+	}
+	if pq := res.Positions[1]; pq.DF != 3 || pq.Entropy != 2 || pq.MaxEntropy != 2 {
+		t.Errorf("uniform position: df %d, entropy %g of %g bits; want 3, 2 of 2", pq.DF, pq.Entropy, pq.MaxEntropy)
+	}
+	if got := res.Summary(); got != "biased positions: 2,4,5; too uniform: 1" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
+
+// TestAnalyzeQualitySeparatorElsewhere checks that a character fixed at
+// one position stays in the alphabet when it also varies elsewhere.
+func TestAnalyzeQualitySeparatorElsewhere(t *testing.T) {
+	counts := qualityCounts(
+		map[byte]int64{'-': 300},
+		map[byte]int64{'a': 100, 'b': 100, '-': 100},
+		map[byte]int64{'a': 150, 'b': 150},
+	)
+	res := analyzeQuality(counts, "ab-", 0.01)
+	if pq := res.Positions[1]; pq.Alphabet != 3 || pq.ChiSquare != 0 || pq.Biased {
+		t.Errorf("position 1: alphabet %d, chi-square %g, biased %t; want 3, 0, false", pq.Alphabet, pq.ChiSquare, pq.Biased)
+	}
+	// 0 '-' where 100 are expected: chi-square 100 + 2·50²/100 = 150.
+	if pq := res.Positions[2]; pq.Alphabet != 3 || pq.ChiSquare != 150 || !pq.Biased {
+		t.Errorf("position 2: alphabet %d, chi-square %g, biased %t; want 3, 150, true", pq.Alphabet, pq.ChiSquare, pq.Biased)
+	}
+}
@@ -0,0 +1,54 @@
+package uidstress
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits the combined rate of several goroutines. It is kept as
+// a theoretical arrival time (GCRA), which behaves like a bucket of burst
+// tokens refilled at rate per second without a refill goroutine.
+type tokenBucket struct {
+	mu       sync.Mutex
+	interval time.Duration // time per token
+	burst    time.Duration // how far ahead of schedule callers may run
+	tat      time.Time     // theoretical arrival time of the next token
+}
+
+// newTokenBucket returns a bucket issuing rate tokens per second. Up to
+// 10ms worth of tokens (at least one) may be taken at once, so that sleep
+// granularity does not pull the achieved rate below the target.
+func newTokenBucket(rate float64) *tokenBucket {
+	interval := max(time.Duration(float64(time.Second)/rate), 1)
+	return &tokenBucket{
+		interval: interval,
+		burst:    max(10*time.Millisecond, interval) - interval,
+	}
+}
+
+// wait blocks until a token is available or ctx is cancelled.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	tat := b.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	allowAt := tat.Add(-b.burst)
+	b.tat = tat.Add(b.interval)
+	b.mu.Unlock()
+
+	d := allowAt.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
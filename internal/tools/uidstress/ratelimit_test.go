@@ -0,0 +1,87 @@
+package uidstress
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTokenBucketRate checks that goroutines sharing a bucket of N tokens
+// per second get about N tokens per second between them.
+func TestTokenBucketRate(t *testing.T) {
+	const (
+		rate   = 1000
+		window = 500 * time.Millisecond
+	)
+	b := newTokenBucket(rate)
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		taken int
+	)
+	deadline := time.Now().Add(window)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if err := b.wait(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				taken++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// The bucket may run 10ms (10 tokens) ahead of schedule.
+	if want := rate * window.Seconds(); float64(taken) < 0.95*want || float64(taken) > 1.05*want+10 {
+		t.Errorf("took %d tokens in %s at %d/s, want about %.0f", taken, window, rate, want)
+	}
+}
+
+// TestTokenBucketCancel checks that a waiting caller returns when its
+// context is cancelled.
+func TestTokenBucketCancel(t *testing.T) {
+	b := newTokenBucket(1)
+	if err := b.wait(context.Background()); err != nil {
+		t.Fatalf("first token: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := b.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second token at 1/s = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("cancelled wait returned after %s", d)
+	}
+}
+
+// TestRunRateAndDuration checks that a run limited to N IDs per second for
+// one second generates about N IDs and stops on time, even though the
+// deadline of unlimited runs is only checked every 64 IDs.
+func TestRunRateAndDuration(t *testing.T) {
+	const rate = 40
+	start := time.Now()
+	results, err := Run(context.Background(), Config{
+		Schemes:  []string{"uuidv4"},
+		Duration: time.Second,
+		Rate:     rate,
+		Workers:  2,
+		TempDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 1500*time.Millisecond {
+		t.Errorf("1s run took %s", d)
+	}
+	if got := results[0].Generated; got < rate*9/10 || got > rate*11/10+1 {
+		t.Errorf("generated %d IDs in 1s at %d/s", got, rate)
+	}
+}
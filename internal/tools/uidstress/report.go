@@ -0,0 +1,279 @@
+package uidstress
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format selects how WriteReport renders results.
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+)
+
+// ParseFormat validates a -format value; "md" is accepted for markdown.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON, FormatCSV, FormatMarkdown:
+		return f, nil
+	case "md":
+		return FormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (want text, json, csv or markdown)", s)
+	}
+}
+
+// Report is everything a command knows about its results. Only the JSON
+// format includes the config and environment.
+type Report struct {
+	// Title heads the text format.
+	Title       string      `json:"-"`
+	Command     string      `json:"command"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Config      any         `json:"config,omitempty"`
+	Environment Environment `json:"environment"`
+	Results     []Result    `json:"results"`
+}
+
+// WriteReport renders rep to w in the given format.
+func WriteReport(w io.Writer, format Format, rep Report) error {
+	switch format {
+	case FormatText, "":
+		return writeText(w, rep)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	case FormatCSV:
+		return writeCSV(w, rep.Results)
+	case FormatMarkdown:
+		return writeMarkdown(w, rep.Results)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// writeText prints the human readable summary block of each result.
+func writeText(w io.Writer, rep Report) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, rep.Title)
+	fmt.Fprintf(&b, "Environment:   %s\n", rep.Environment)
+	fmt.Fprintln(&b, strings.Repeat("=", 72))
+	for _, res := range rep.Results {
+		fmt.Fprintf(&b, "Scheme:        %s\n", res.Scheme)
+		fmt.Fprintf(&b, "Duration:      %s\n", res.Duration.Round(time.Millisecond))
+		fmt.Fprintf(&b, "Chunks:        %d\n", res.Chunks)
+		fmt.Fprintf(&b, "Generated:     %d\n", res.Generated)
+		fmt.Fprintf(&b, "Chunk Unique:  %d\n", res.ChunkUnique)
+		fmt.Fprintf(&b, "Unique:        %d\n", res.Unique)
+		fmt.Fprintf(&b, "Duplicates:    %d (intra-chunk %d, cross-chunk %d)\n",
+			res.TotalDuplicates, res.IntraChunkDuplicates, res.Duplicates)
+		if res.TruncateBits > 0 {
+			fmt.Fprintf(&b, "Birthday:      expected %.2f ± %.2f at %d bits, observed z = %+.2f\n",
+				res.ExpectedDuplicates, res.ExpectedDuplicatesStdDev, res.TruncateBits, res.DuplicatesZScore())
+		}
+		if res.Sources > 0 {
+			fmt.Fprintf(&b, "Sources:       %d\n", res.Sources)
+			fmt.Fprintf(&b, "Cross-source:  %d\n", res.CrossSourceDuplicates)
+		}
+		if res.Sortable && !res.OrderTracked {
+			fmt.Fprintf(&b, "Order Viol.:   %d\n", res.OrderViolations)
+		}
+		if res.OrderTracked {
+			fmt.Fprintf(&b, "Inversions:    per-worker %d, global %d\n", res.OrderViolations, res.GlobalOrderViolations)
+			fmt.Fprintf(&b, "Disorder Run:  per-worker %d, global %d\n", res.LongestWorkerDisorder, res.LongestGlobalDisorder)
+			if res.TimeOrderChecked > 0 {
+				fmt.Fprintf(&b, "Time Order:    %d of %d sorted pairs go back in time\n", res.TimeOrderViolations, res.TimeOrderChecked)
+			}
+		}
+		if res.GenerateErrors > 0 {
+			fmt.Fprintf(&b, "Gen Errors:    %d\n", res.GenerateErrors)
+		}
+		if res.Latency != nil {
+			fmt.Fprintf(&b, "Latency:       %s\n", res.Latency)
+		}
+		if res.DuplicatesReport != "" {
+			fmt.Fprintf(&b, "Dup Report:    %s\n", res.DuplicatesReport)
+		}
+		if res.ManifestPath != "" {
+			fmt.Fprintf(&b, "Manifest:      %s\n", res.ManifestPath)
+		}
+		if res.OutputDir != "" {
+			fmt.Fprintf(&b, "Temp Dir:      %s\n", res.OutputDir)
+		}
+		fmt.Fprintln(&b, strings.Repeat("-", 72))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCSV writes one row per result with a header row.
+func writeCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"scheme", "duration_seconds", "chunks", "generated", "chunk_unique", "unique",
+		"uniqueness_percent", "total_duplicates", "intra_chunk_duplicates", "cross_chunk_duplicates",
+		"sources", "cross_source_duplicates", "sortable", "order_violations", "generate_errors",
+		"duplicates_report", "global_order_violations", "longest_worker_disorder", "longest_global_disorder",
+		"time_order_checked", "time_order_violations", "latency_samples", "latency_p50_ns", "latency_p90_ns",
+		"latency_p99_ns", "latency_p999_ns", "latency_max_ns", "truncate_bits", "expected_duplicates",
+		"expected_duplicates_stddev",
+	})
+	for _, res := range results {
+		latency := make([]string, 6)
+		if l := res.Latency; l != nil {
+			for i, v := range []int64{l.Samples, int64(l.P50), int64(l.P90), int64(l.P99), int64(l.P999), int64(l.Max)} {
+				latency[i] = strconv.FormatInt(v, 10)
+			}
+		}
+		birthday := make([]string, 3)
+		if res.TruncateBits > 0 {
+			birthday[0] = strconv.Itoa(res.TruncateBits)
+			birthday[1] = strconv.FormatFloat(res.ExpectedDuplicates, 'f', -1, 64)
+			birthday[2] = strconv.FormatFloat(res.ExpectedDuplicatesStdDev, 'f', -1, 64)
+		}
+		cw.Write(append([]string{
+			res.Scheme,
+			strconv.FormatFloat(res.Duration.Seconds(), 'f', 3, 64),
+			strconv.Itoa(res.Chunks),
+			strconv.FormatInt(res.Generated, 10),
+			strconv.FormatInt(res.ChunkUnique, 10),
+			strconv.FormatInt(res.Unique, 10),
+			strconv.FormatFloat(res.Uniqueness(), 'f', -1, 64),
+			strconv.FormatInt(res.TotalDuplicates, 10),
+			strconv.FormatInt(res.IntraChunkDuplicates, 10),
+			strconv.FormatInt(res.Duplicates, 10),
+			strconv.Itoa(res.Sources),
+			strconv.FormatInt(res.CrossSourceDuplicates, 10),
+			strconv.FormatBool(res.Sortable),
+			strconv.FormatInt(res.OrderViolations, 10),
+			strconv.FormatInt(res.GenerateErrors, 10),
+			res.DuplicatesReport,
+			strconv.FormatInt(res.GlobalOrderViolations, 10),
+			strconv.FormatInt(res.LongestWorkerDisorder, 10),
+			strconv.FormatInt(res.LongestGlobalDisorder, 10),
+			strconv.FormatInt(res.TimeOrderChecked, 10),
+			strconv.FormatInt(res.TimeOrderViolations, 10),
+		}, append(latency, birthday...)...))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMarkdown writes the table used in the README's stress test results,
+// with latency percentile columns when any result sampled latency.
+func writeMarkdown(w io.Writer, results []Result) error {
+	withLatency := false
+	for _, res := range results {
+		withLatency = withLatency || res.Latency != nil
+	}
+	header := []string{"Scheme", "耗时", "生成数量", "唯一性", "重复数"}
+	if withLatency {
+		header = append(header, "p50", "p90", "p99", "p99.9", "最大延迟")
+	}
+	rows := [][]string{header}
+	for _, res := range results {
+		row := []string{
+			res.Scheme,
+			res.Duration.Round(time.Millisecond).String(),
+			groupDigits(res.Generated),
+			formatPercent(res.Uniqueness()),
+			groupDigits(res.TotalDuplicates),
+		}
+		if l := res.Latency; l != nil {
+			row = append(row, l.P50.String(), l.P90.String(), l.P99.String(), l.P999.String(), l.Max.String())
+		} else if withLatency {
+			row = append(row, "-", "-", "-", "-", "-")
+		}
+		rows = append(rows, row)
+	}
+	return writeTable(w, rows)
+}
+
+// writeTable writes rows as a Markdown table padded to the widest cell of
+// each column, counting CJK characters as two columns.
+func writeTable(w io.Writer, rows [][]string) error {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	var b strings.Builder
+	for r, row := range rows {
+		b.WriteString("|")
+		for i, cell := range row {
+			b.WriteString(" " + cell + strings.Repeat(" ", widths[i]-displayWidth(cell)) + " |")
+		}
+		b.WriteString("\n")
+		if r == 0 {
+			b.WriteString("|")
+			for _, width := range widths {
+				b.WriteString(strings.Repeat("-", width+2) + "|")
+			}
+			b.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// displayWidth approximates the display width of s: characters from the
+// Hangul Jamo block on (which covers CJK) count as two columns.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// groupDigits formats n with comma thousands separators.
+func groupDigits(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte(s[i])
+	}
+	if neg {
+		return "-" + b.String()
+	}
+	return b.String()
+}
+
+// formatPercent renders p with as few decimals as needed, so complete
+// uniqueness reads "100%" while a single duplicate among billions still
+// shows as less than 100%.
+func formatPercent(p float64) string {
+	var s string
+	for prec := 4; prec <= 15; prec++ {
+		s = strconv.FormatFloat(p, 'f', prec, 64)
+		if p >= 100 || !strings.HasPrefix(s, "100") {
+			break
+		}
+	}
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s + "%"
+}
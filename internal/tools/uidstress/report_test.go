@@ -0,0 +1,134 @@
+package uidstress
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportResults are the results the golden tests render: a sortable run
+// with a duplicate and sampled latency, and a truncated run without.
+var reportResults = []Result{
+	{
+		Scheme:           "ulid",
+		Chunks:           2,
+		Duration:         1234567890 * time.Nanosecond,
+		Generated:        1234567,
+		ChunkUnique:      1234567,
+		Unique:           1234566,
+		Duplicates:       1,
+		TotalDuplicates:  1,
+		Sortable:         true,
+		DuplicatesReport: "ulid-duplicates.ndjson",
+		Latency: &Latency{
+			Samples: 1000,
+			P50:     120 * time.Nanosecond,
+			P90:     250 * time.Nanosecond,
+			P99:     1500 * time.Nanosecond,
+			P999:    12 * time.Microsecond,
+			Max:     3 * time.Millisecond,
+		},
+	},
+	{
+		Scheme:                   "uuidv4",
+		Chunks:                   1,
+		Duration:                 500 * time.Millisecond,
+		Generated:                1000,
+		ChunkUnique:              998,
+		Unique:                   997,
+		Duplicates:               1,
+		IntraChunkDuplicates:     2,
+		TotalDuplicates:          3,
+		TruncateBits:             20,
+		ExpectedDuplicates:       0.4764,
+		ExpectedDuplicatesStdDev: 0.69,
+	},
+}
+
+const csvHeader = "scheme,duration_seconds,chunks,generated,chunk_unique,unique,uniqueness_percent," +
+	"total_duplicates,intra_chunk_duplicates,cross_chunk_duplicates,sources,cross_source_duplicates,sortable," +
+	"order_violations,generate_errors,duplicates_report,global_order_violations,longest_worker_disorder," +
+	"longest_global_disorder,time_order_checked,time_order_violations,latency_samples,latency_p50_ns," +
+	"latency_p90_ns,latency_p99_ns,latency_p999_ns,latency_max_ns,truncate_bits,expected_duplicates," +
+	"expected_duplicates_stddev\n"
+
+// TestWriteReportGolden renders the results in each format, with and
+// without latency data, and compares the output byte for byte.
+func TestWriteReportGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		results []Result
+		want    string
+	}{
+		{"csv", FormatCSV, reportResults, csvHeader +
+			"ulid,1.235,2,1234567,1234567,1234566,99.99991899994087,1,0,1,0,0,true,0,0,ulid-duplicates.ndjson,0,0,0,0,0,1000,120,250,1500,12000,3000000,,,\n" +
+			"uuidv4,0.500,1,1000,998,997,99.7,3,2,1,0,0,false,0,0,,0,0,0,0,0,,,,,,,20,0.4764,0.69\n"},
+		{"csv without latency", FormatCSV, reportResults[1:], csvHeader +
+			"uuidv4,0.500,1,1000,998,997,99.7,3,2,1,0,0,false,0,0,,0,0,0,0,0,,,,,,,20,0.4764,0.69\n"},
+		{"markdown", FormatMarkdown, reportResults, "" +
+			"| Scheme | 耗时   | 生成数量  | 唯一性   | 重复数 | p50   | p90   | p99   | p99.9 | 最大延迟 |\n" +
+			"|--------|--------|-----------|----------|--------|-------|-------|-------|-------|----------|\n" +
+			"| ulid   | 1.235s | 1,234,567 | 99.9999% | 1      | 120ns | 250ns | 1.5µs | 12µs  | 3ms      |\n" +
+			"| uuidv4 | 500ms  | 1,000     | 99.7%    | 3      | -     | -     | -     | -     | -        |\n"},
+		{"markdown without latency", FormatMarkdown, reportResults[1:], "" +
+			"| Scheme | 耗时  | 生成数量 | 唯一性 | 重复数 |\n" +
+			"|--------|-------|----------|--------|--------|\n" +
+			"| uuidv4 | 500ms | 1,000    | 99.7%  | 3      |\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := WriteReport(&b, tt.format, Report{Results: tt.results}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestFormatPercent checks that only as many decimals are shown as it
+// takes to tell a shortfall from 100%.
+func TestFormatPercent(t *testing.T) {
+	tests := []struct {
+		p    float64
+		want string
+	}{
+		{100, "100%"},
+		{0, "0%"},
+		{99.7, "99.7%"},
+		{50, "50%"},
+		{12.34567, "12.3457%"},
+		{100 * 999999 / 1e6, "99.9999%"},
+		{100 * (1 - 1e-7), "99.99999%"},
+		{100 * (1 - 1e-12), "99.9999999999%"},
+	}
+	for _, tt := range tests {
+		if got := formatPercent(tt.p); got != tt.want {
+			t.Errorf("formatPercent(%v) = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+}
+
+// TestGroupDigits checks the thousands separators, including negative
+// numbers and lengths on both sides of a group boundary.
+func TestGroupDigits(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{123456, "123,456"},
+		{1234567, "1,234,567"},
+		{-1234, "-1,234"},
+		{-999, "-999"},
+		{9223372036854775807, "9,223,372,036,854,775,807"},
+	}
+	for _, tt := range tests {
+		if got := groupDigits(tt.n); got != tt.want {
+			t.Errorf("groupDigits(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
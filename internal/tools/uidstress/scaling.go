@@ -0,0 +1,176 @@
+package uidstress
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// ScalingConfig controls a concurrency scaling run: every scheme generates
+// for a fixed time at each goroutine count, keeping no IDs.
+type ScalingConfig struct {
+	// Schemes lists the schemes to measure; empty or "all" means every
+	// registered scheme.
+	Schemes []string
+	// Goroutines lists the goroutine counts to measure. Empty means powers
+	// of two up to MaxGoroutines, plus MaxGoroutines itself.
+	Goroutines []int
+	// MaxGoroutines bounds the default goroutine counts (default: NumCPU).
+	MaxGoroutines int
+	// Duration is how long each scheme generates at each goroutine count.
+	Duration time.Duration
+	// Warmup runs each scheme single-threaded before its first point.
+	Warmup time.Duration
+	// VaryGOMAXPROCS sets GOMAXPROCS to the goroutine count for each point,
+	// so that n goroutines run on n Ps instead of all available ones.
+	VaryGOMAXPROCS bool
+	NodeID         int64
+	Layout         string
+}
+
+// ScalingPoint is the throughput of one scheme at one goroutine count.
+type ScalingPoint struct {
+	Goroutines int           `json:"goroutines"`
+	GOMAXPROCS int           `json:"gomaxprocs"`
+	Generated  int64         `json:"generated"`
+	Duration   time.Duration `json:"duration_ns"`
+	// Rate is Generated per second.
+	Rate float64 `json:"rate"`
+	// Speedup is Rate relative to the scheme's first point.
+	Speedup float64 `json:"speedup"`
+	// Efficiency is Speedup per goroutine relative to the first point:
+	// 1 means perfect linear scaling, 1/Goroutines means no gain at all.
+	Efficiency float64 `json:"efficiency"`
+}
+
+// ScalingResult holds the scaling curve of one scheme.
+type ScalingResult struct {
+	Scheme string         `json:"scheme"`
+	Points []ScalingPoint `json:"points"`
+}
+
+// Scaling measures the throughput of each scheme at each goroutine count.
+func Scaling(ctx context.Context, cfg ScalingConfig) ([]ScalingResult, error) {
+	if len(cfg.Schemes) == 0 {
+		cfg.Schemes = []string{"all"}
+	}
+	if cfg.MaxGoroutines <= 0 {
+		cfg.MaxGoroutines = runtime.NumCPU()
+	}
+	if len(cfg.Goroutines) == 0 {
+		cfg.Goroutines = scalingCounts(cfg.MaxGoroutines)
+	}
+	for _, n := range cfg.Goroutines {
+		if n <= 0 {
+			return nil, fmt.Errorf("goroutine count must be > 0, got %d", n)
+		}
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = time.Second
+	}
+	if cfg.Warmup < 0 {
+		cfg.Warmup = 0
+	}
+	schemes, err := resolveSchemes(cfg.Schemes)
+	if err != nil {
+		return nil, err
+	}
+
+	procs := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(procs)
+
+	results := make([]ScalingResult, 0, len(schemes))
+	for _, scheme := range schemes {
+		gen, err := newGenerator(scheme, Config{NodeID: cfg.NodeID, Layout: cfg.Layout})
+		if err != nil {
+			return nil, err
+		}
+		runtime.GOMAXPROCS(procs)
+		generateFor(ctx, gen, 1, cfg.Warmup)
+
+		res := ScalingResult{Scheme: gen.Name()}
+		for _, n := range cfg.Goroutines {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if cfg.VaryGOMAXPROCS {
+				runtime.GOMAXPROCS(n)
+			}
+			runtime.GC()
+			generated, elapsed := generateFor(ctx, gen, n, cfg.Duration)
+			p := ScalingPoint{
+				Goroutines: n,
+				GOMAXPROCS: runtime.GOMAXPROCS(0),
+				Generated:  generated,
+				Duration:   elapsed,
+				Rate:       float64(generated) / elapsed.Seconds(),
+			}
+			if len(res.Points) > 0 && res.Points[0].Rate > 0 {
+				base := res.Points[0]
+				p.Speedup = p.Rate / base.Rate
+				p.Efficiency = p.Speedup * float64(base.Goroutines) / float64(n)
+			} else {
+				p.Speedup, p.Efficiency = 1, 1
+			}
+			res.Points = append(res.Points, p)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// scalingCounts returns 1, 2, 4, ... below limit, then limit itself.
+func scalingCounts(limit int) []int {
+	var counts []int
+	for n := 1; n < limit; n *= 2 {
+		counts = append(counts, n)
+	}
+	return append(counts, limit)
+}
+
+// generateFor calls gen from goroutines goroutines until d has passed or
+// ctx is cancelled, and returns the number of IDs and the time taken.
+func generateFor(ctx context.Context, gen tools.Generator, goroutines int, d time.Duration) (int64, time.Duration) {
+	if d <= 0 {
+		return 0, 0
+	}
+	var (
+		wg    sync.WaitGroup
+		stop  atomic.Bool
+		total atomic.Int64
+	)
+	start := time.Now()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var (
+				n  int64
+				id string
+			)
+			// Check the stop flag in batches to keep it off the hot path.
+			for !stop.Load() {
+				for i := 0; i < 256; i++ {
+					id = gen.Generate()
+				}
+				n += 256
+			}
+			total.Add(n)
+			sink.Add(int64(len(id)))
+		}()
+	}
+	timer := time.NewTimer(d)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+	stop.Store(true)
+	wg.Wait()
+	return total.Load(), time.Since(start)
+}
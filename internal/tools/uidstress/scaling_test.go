@@ -0,0 +1,79 @@
+package uidstress
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+// closeTo reports whether got is within rel of want, relative to want.
+func closeTo(got, want, rel float64) bool {
+	return math.Abs(got-want) <= rel*math.Abs(want)
+}
+
+// TestScalingCounts checks the default goroutine counts.
+func TestScalingCounts(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 2}},
+		{6, []int{1, 2, 4, 6}},
+		{8, []int{1, 2, 4, 8}},
+	}
+	for _, tt := range tests {
+		if got := scalingCounts(tt.limit); !slices.Equal(got, tt.want) {
+			t.Errorf("scalingCounts(%d) = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
+
+// TestScaling runs testseq at 1 and 2 goroutines and checks the shape of
+// the result and that rate, speedup and efficiency follow from the counts.
+func TestScaling(t *testing.T) {
+	script([]string{"a"}, 0)
+	procs := runtime.GOMAXPROCS(0)
+	results, err := Scaling(context.Background(), ScalingConfig{
+		Schemes:        []string{"testseq"},
+		Goroutines:     []int{1, 2},
+		Duration:       20 * time.Millisecond,
+		VaryGOMAXPROCS: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runtime.GOMAXPROCS(0); got != procs {
+		t.Errorf("GOMAXPROCS left at %d, want %d restored", got, procs)
+	}
+	if len(results) != 1 || results[0].Scheme != "testseq" || len(results[0].Points) != 2 {
+		t.Fatalf("results %+v, want testseq at two goroutine counts", results)
+	}
+	base := results[0].Points[0]
+	for i, p := range results[0].Points {
+		if p.Goroutines != i+1 || p.GOMAXPROCS != i+1 {
+			t.Errorf("point %d: %d goroutines on GOMAXPROCS %d, want %d on %d", i, p.Goroutines, p.GOMAXPROCS, i+1, i+1)
+		}
+		// Goroutines generate in batches of 256 and run at least the duration.
+		if p.Generated <= 0 || p.Generated%256 != 0 || p.Duration < 20*time.Millisecond {
+			t.Errorf("point %d: %d IDs in %s, want a positive multiple of 256 in at least 20ms", i, p.Generated, p.Duration)
+		}
+		if rate := float64(p.Generated) / p.Duration.Seconds(); !closeTo(p.Rate, rate, 1e-12) {
+			t.Errorf("point %d: rate %g, want %g", i, p.Rate, rate)
+		}
+		speedup := p.Rate / base.Rate
+		if !closeTo(p.Speedup, speedup, 1e-12) || !closeTo(p.Efficiency, speedup/float64(p.Goroutines), 1e-12) {
+			t.Errorf("point %d: speedup %g, efficiency %g; want %g, %g", i, p.Speedup, p.Efficiency, speedup, speedup/float64(p.Goroutines))
+		}
+	}
+	if base.Speedup != 1 || base.Efficiency != 1 {
+		t.Errorf("first point: speedup %g, efficiency %g; want 1, 1", base.Speedup, base.Efficiency)
+	}
+
+	if _, err := Scaling(context.Background(), ScalingConfig{Schemes: []string{"testseq"}, Goroutines: []int{1, 0}}); err == nil {
+		t.Error("Scaling accepted a goroutine count of 0")
+	}
+}
@@ -0,0 +1,267 @@
+package uidstress
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary describes a sample of repeated measurements.
+type Summary struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stddev"`
+	// CILow and CIHigh bound the 95% confidence interval of the mean
+	// (Student's t with N-1 degrees of freedom).
+	CILow  float64 `json:"ci95_low"`
+	CIHigh float64 `json:"ci95_high"`
+}
+
+// summarize computes the summary of samples.
+func summarize(samples []float64) Summary {
+	n := len(samples)
+	if n == 0 {
+		return Summary{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	s := Summary{N: n, Mean: mean(samples)}
+	if n%2 == 1 {
+		s.Median = sorted[n/2]
+	} else {
+		s.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	s.CILow, s.CIHigh = s.Mean, s.Mean
+	if n > 1 {
+		s.StdDev = math.Sqrt(variance(samples, s.Mean))
+		half := studentTQuantile(0.975, float64(n-1)) * s.StdDev / math.Sqrt(float64(n))
+		s.CILow, s.CIHigh = s.Mean-half, s.Mean+half
+	}
+	return s
+}
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// variance returns the unbiased sample variance of xs around m.
+func variance(xs []float64, m float64) float64 {
+	var ss float64
+	for _, x := range xs {
+		ss += (x - m) * (x - m)
+	}
+	return ss / float64(len(xs)-1)
+}
+
+// welchTTest returns the two-sided p-value of Welch's t-test for equal
+// means of a and b, which need not have equal variances.
+func welchTTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+	ma, mb := mean(a), mean(b)
+	va, vb := variance(a, ma)/float64(len(a)), variance(b, mb)/float64(len(b))
+	if va+vb == 0 {
+		if ma == mb {
+			return 1
+		}
+		return 0
+	}
+	t := (ma - mb) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	return 2 * (1 - studentTCDF(math.Abs(t), df))
+}
+
+// studentTCDF is the cumulative distribution function of Student's t with
+// df degrees of freedom.
+func studentTCDF(t, df float64) float64 {
+	x := df / (df + t*t)
+	tail := 0.5 * regIncBeta(df/2, 0.5, x)
+	if t >= 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// studentTQuantile inverts studentTCDF by bisection.
+func studentTQuantile(p, df float64) float64 {
+	lo, hi := -1e3, 1e3
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		if studentTCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with the continued fraction from Numerical Recipes.
+func regIncBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly only below the mean.
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+	}
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-15
+		tiny    = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
+
+// chiSquareSurvival returns P(X >= x) for a chi-square distribution with df
+// degrees of freedom: the p-value of a goodness-of-fit statistic x.
+func chiSquareSurvival(x float64, df int) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return regIncGammaQ(float64(df)/2, x/2)
+}
+
+// regIncGammaQ is the regularized upper incomplete gamma function Q(a, x),
+// evaluated with the series or continued fraction from Numerical Recipes.
+func regIncGammaQ(a, x float64) float64 {
+	const (
+		maxIter = 1000
+		eps     = 1e-15
+		tiny    = 1e-300
+	)
+	lga, _ := math.Lgamma(a)
+	front := math.Exp(-x + a*math.Log(x) - lga)
+	if x < a+1 {
+		// Series for P(a, x).
+		ap, sum := a, 1/a
+		del := sum
+		for n := 0; n < maxIter; n++ {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return 1 - sum*front
+	}
+	// Continued fraction for Q(a, x).
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= maxIter; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return front * h
+}
+
+// ksStatistic returns the two-sided Kolmogorov-Smirnov distance between
+// the sorted integer sample xs and the distribution with CDF cdf. Ties are
+// compared as a step, so discrete distributions are handled exactly.
+func ksStatistic(xs []int64, cdf func(x int64) float64) float64 {
+	n := float64(len(xs))
+	var d float64
+	for i := 0; i < len(xs); {
+		j := i
+		for j < len(xs) && xs[j] == xs[i] {
+			j++
+		}
+		d = max(d, math.Abs(float64(j)/n-cdf(xs[i])), math.Abs(float64(i)/n-cdf(xs[i]-1)))
+		i = j
+	}
+	return d
+}
+
+// ksSurvival returns the asymptotic p-value of a Kolmogorov-Smirnov
+// distance d from a sample of size n, with Stephens' small-sample
+// correction. It is conservative for discrete distributions.
+func ksSurvival(d float64, n int) float64 {
+	sn := math.Sqrt(float64(n))
+	lambda := (sn + 0.12 + 0.11/sn) * d
+	if lambda < 0.2 {
+		return 1
+	}
+	var p float64
+	sign := 1.0
+	for k := 1; k <= 100; k++ {
+		term := sign * math.Exp(-2*float64(k*k)*lambda*lambda)
+		p += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return min(max(2*p, 0), 1)
+}
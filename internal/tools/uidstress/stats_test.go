@@ -0,0 +1,159 @@
+package uidstress
+
+import (
+	"math"
+	"testing"
+)
+
+// TestStudentTQuantile checks two-sided 95% and 99% critical values against
+// the published t table.
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct {
+		p, df, want float64
+	}{
+		{0.975, 1, 12.7062},
+		{0.975, 5, 2.5706},
+		{0.975, 30, 2.0423},
+		{0.975, 1e7, 1.9600}, // df = ∞: the normal quantile
+		{0.995, 1, 63.6567},
+		{0.995, 10, 3.1693},
+		{0.995, 1e7, 2.5758},
+		{0.5, 7, 0},
+		{0.025, 5, -2.5706},
+	}
+	for _, tt := range tests {
+		if got := studentTQuantile(tt.p, tt.df); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("studentTQuantile(%g, %g) = %.5f, want %.4f", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+// TestRegIncBeta checks the incomplete beta function against closed forms.
+func TestRegIncBeta(t *testing.T) {
+	tests := []struct {
+		a, b, x, want float64
+	}{
+		{1, 1, 0.3, 0.3},                  // uniform CDF
+		{3, 1, 0.6, 0.216},                // x^a
+		{1, 4, 0.2, 1 - math.Pow(0.8, 4)}, // 1-(1-x)^b
+		{7.5, 7.5, 0.5, 0.5},              // symmetry
+		{2, 3, 0.9, 1 - 0.0037},           // both continued-fraction branches
+		{0.5, 0.5, 0.25, 1.0 / 3},         // arcsine CDF
+		{2, 2, 0, 0},
+		{2, 2, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := regIncBeta(tt.a, tt.b, tt.x); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("regIncBeta(%g, %g, %g) = %.12f, want %.12f", tt.a, tt.b, tt.x, got, tt.want)
+		}
+	}
+}
+
+// TestChiSquareSurvival checks upper-tail probabilities at published
+// critical values of the chi-square distribution.
+func TestChiSquareSurvival(t *testing.T) {
+	tests := []struct {
+		x    float64
+		df   int
+		want float64
+	}{
+		{3.8415, 1, 0.05},
+		{6.6349, 1, 0.01},
+		{2, 2, math.Exp(-1)}, // df 2 is exponential with mean 2
+		{18.3070, 10, 0.05},
+		{23.2093, 10, 0.01},
+		{124.3421, 100, 0.05},
+		{77.9295, 100, 0.95},
+		{0, 5, 1},
+	}
+	for _, tt := range tests {
+		if got := chiSquareSurvival(tt.x, tt.df); math.Abs(got-tt.want) > 1e-5 {
+			t.Errorf("chiSquareSurvival(%g, %d) = %.6f, want %.6f", tt.x, tt.df, got, tt.want)
+		}
+	}
+}
+
+// TestKSSurvival checks the asymptotic Kolmogorov-Smirnov critical values
+// 1.2239, 1.3581 and 1.6276 for alpha 0.10, 0.05 and 0.01.
+func TestKSSurvival(t *testing.T) {
+	const n = 1_000_000
+	tests := []struct {
+		lambda, want float64
+	}{
+		{1.2239, 0.10},
+		{1.3581, 0.05},
+		{1.6276, 0.01},
+		{0.1, 1},
+	}
+	for _, tt := range tests {
+		d := tt.lambda / math.Sqrt(n)
+		if got := ksSurvival(d, n); math.Abs(got-tt.want) > 5e-4 {
+			t.Errorf("ksSurvival(%g/sqrt(n), %d) = %.5f, want %.2f", tt.lambda, n, got, tt.want)
+		}
+	}
+}
+
+// TestKSStatistic checks the distance of small samples from a discrete
+// uniform distribution on 0..9.
+func TestKSStatistic(t *testing.T) {
+	cdf := func(x int64) float64 { return min(max(float64(x+1)/10, 0), 1) }
+	tests := []struct {
+		xs   []int64
+		want float64
+	}{
+		{[]int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 0},
+		{[]int64{0, 0, 0, 0, 0}, 0.9},
+		{[]int64{9, 9}, 0.9},
+		{[]int64{0, 9}, 0.4},
+	}
+	for _, tt := range tests {
+		if got := ksStatistic(tt.xs, cdf); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("ksStatistic(%v) = %g, want %g", tt.xs, got, tt.want)
+		}
+	}
+}
+
+// TestWelchTTest checks the Welch test on the worked example from the
+// literature (t = -2.46, df = 24.9, p = 0.021) and on degenerate samples.
+func TestWelchTTest(t *testing.T) {
+	a := []float64{27.5, 21.0, 19.0, 23.6, 17.0, 17.9, 16.9, 20.1, 21.9, 22.6, 23.1, 19.6, 19.0, 21.7, 21.4}
+	b := []float64{27.1, 22.0, 20.8, 23.4, 23.4, 23.5, 25.8, 22.0, 24.8, 20.2, 21.9, 22.1, 22.9, 20.5, 24.4}
+	if got := welchTTest(a, b); math.Abs(got-0.021) > 0.001 {
+		t.Errorf("welchTTest(example) = %.4f, want 0.021", got)
+	}
+	if got, rev := welchTTest(a, b), welchTTest(b, a); got != rev {
+		t.Errorf("welchTTest is not symmetric: %g vs %g", got, rev)
+	}
+	if got := welchTTest(a, a); math.Abs(got-1) > 1e-12 {
+		t.Errorf("welchTTest(a, a) = %g, want 1", got)
+	}
+	if got := welchTTest([]float64{1, 1}, []float64{2, 2}); got != 0 {
+		t.Errorf("welchTTest(constant samples with different means) = %g, want 0", got)
+	}
+	if got := welchTTest([]float64{1}, b); !math.IsNaN(got) {
+		t.Errorf("welchTTest(single sample) = %g, want NaN", got)
+	}
+}
+
+// TestSummarize checks the mean, median, standard deviation and t interval
+// of a small sample.
+func TestSummarize(t *testing.T) {
+	s := summarize([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if s.N != 8 || s.Mean != 5 || s.Median != 4.5 {
+		t.Fatalf("summarize = %+v, want N 8, mean 5, median 4.5", s)
+	}
+	sd := math.Sqrt(32.0 / 7)
+	if math.Abs(s.StdDev-sd) > 1e-12 {
+		t.Errorf("StdDev = %g, want %g", s.StdDev, sd)
+	}
+	half := 2.3646 * sd / math.Sqrt(8) // t(0.975, 7)
+	if math.Abs(s.CILow-(5-half)) > 1e-3 || math.Abs(s.CIHigh-(5+half)) > 1e-3 {
+		t.Errorf("CI = [%g, %g], want 5 ± %g", s.CILow, s.CIHigh, half)
+	}
+	if s := summarize([]float64{3}); s.CILow != 3 || s.CIHigh != 3 || s.StdDev != 0 {
+		t.Errorf("summarize(single) = %+v, want a zero-width interval", s)
+	}
+	if s := summarize(nil); s != (Summary{}) {
+		t.Errorf("summarize(nil) = %+v, want zero", s)
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/disk"
@@ -30,61 +32,254 @@ const (
 
 // Config controls how the stress test runs.
 type Config struct {
-	Schemes          []string
-	Scale            int64
-	ChunkSize        int64
-	Workers          int
-	TempDir          string
-	KeepTempData     bool
-	LogInterval      int64
-	Verbose          bool
-	ApproxBytesPerID int64
-	MemGuardMB       float64
-	DiskSafetyFactor float64
+	Schemes []string `json:"schemes"`
+	// Scale is the number of IDs to generate per scheme. With Duration set
+	// it is an upper bound, and 0 means no bound.
+	Scale            int64   `json:"scale"`
+	ChunkSize        int64   `json:"chunk_size"`
+	Workers          int     `json:"workers"`
+	TempDir          string  `json:"temp_dir,omitempty"`
+	KeepTempData     bool    `json:"keep_temp_data"`
+	LogInterval      int64   `json:"log_interval"`
+	Verbose          bool    `json:"verbose"`
+	ApproxBytesPerID int64   `json:"approx_bytes_per_id"`
+	MemGuardMB       float64 `json:"mem_guard_mb"`
+	DiskSafetyFactor float64 `json:"disk_safety_factor"`
+	// NodeID is passed to schemes that embed a node ID (e.g. snowflake);
+	// other schemes ignore it.
+	NodeID int64 `json:"node_id,omitempty"`
+	// Layout is passed to schemes with a configurable bit layout (e.g.
+	// customuid, see tools.ParseCustomUIDLayout); other schemes ignore it.
+	Layout string `json:"layout,omitempty"`
+	// Clock replaces the wall clock of time-based schemes, see parseClock
+	// for the accepted specs ("system", "frozen", "skew:...").
+	Clock string `json:"clock,omitempty"`
+	// ResumeDir continues an interrupted run from the manifest.json in this
+	// directory. Scheme, scale and chunk size are taken from the manifest.
+	// The directory is kept after the run, even without KeepTempData.
+	ResumeDir string `json:"resume_dir,omitempty"`
+	// StartAt delays generation until the given instant so that several
+	// processes can be lined up to start in the same second.
+	StartAt time.Time `json:"start_at,omitzero"`
+	// LatencySample times every Nth ID of each worker into a latency
+	// histogram (1 times every ID, 0 disables sampling). Timing adds the
+	// cost of two clock reads to each sampled call.
+	LatencySample int64 `json:"latency_sample,omitempty"`
+	// Duration stops generating each scheme once this much wall-clock time
+	// has been spent on it, even if Scale has not been reached.
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	// Rate limits generation to this many IDs per second across all
+	// workers (0 means as fast as possible).
+	Rate float64 `json:"rate,omitempty"`
+	// TrackOrder records the order in which IDs are returned across all
+	// workers and checks lexicographic order against embedded timestamps,
+	// see Result.GlobalOrderViolations. It adds a shared atomic counter to
+	// every call.
+	TrackOrder bool `json:"track_order,omitempty"`
+	// TruncateBits, when set, replaces every ID by the low TruncateBits
+	// bits of its random portion before it is chunked and deduplicated, so
+	// that collisions become frequent enough to compare with the birthday
+	// bound, see Result.ExpectedDuplicates. Schemes without an extractable
+	// random portion (tools.RandomExtractor) cannot be truncated.
+	TruncateBits int `json:"truncate_bits,omitempty"`
 }
 
 // Result captures the summary for each scheme.
 type Result struct {
-	Scheme       string
-	Chunks       int
-	Duration     time.Duration
-	Generated    int64
-	ChunkUnique  int64
-	Unique       int64
-	Duplicates   int64
-	ManifestPath string
-	OutputDir    string
+	Scheme   string        `json:"scheme"`
+	Chunks   int           `json:"chunks"`
+	Duration time.Duration `json:"duration_ns"`
+	// GenerateTime is the part of Duration spent generating IDs, without
+	// sorting, writing or merging them; summed over the runs of a merge.
+	GenerateTime time.Duration `json:"generate_ns,omitempty"`
+	Generated    int64         `json:"generated"`
+	ChunkUnique  int64         `json:"chunk_unique"`
+	Unique       int64         `json:"unique"`
+	// Duplicates counts collisions between different chunks, found while merging.
+	Duplicates int64 `json:"duplicates"`
+	// IntraChunkDuplicates counts collisions inside a single chunk, dropped
+	// before the chunk is written.
+	IntraChunkDuplicates int64 `json:"intra_chunk_duplicates"`
+	// TotalDuplicates is Generated - Unique.
+	TotalDuplicates int64 `json:"total_duplicates"`
+	// Sortable reports whether the scheme claims time-sortable IDs.
+	Sortable bool `json:"sortable"`
+	// OrderViolations counts IDs that sort before the previous ID generated
+	// by the same worker goroutine.
+	OrderViolations int64 `json:"order_violations"`
+	// OrderTracked is set when the fields below were recorded (TrackOrder).
+	OrderTracked bool `json:"order_tracked,omitempty"`
+	// GlobalOrderViolations counts IDs that sort before the ID returned
+	// just before them by any worker of the same chunk.
+	GlobalOrderViolations int64 `json:"global_order_violations,omitempty"`
+	// LongestWorkerDisorder and LongestGlobalDisorder are the longest runs
+	// of consecutive IDs, of one worker or of all workers of a chunk, that
+	// sort before the largest ID returned earlier: how long order took to
+	// recover, e.g. after a clock rollback.
+	LongestWorkerDisorder int64 `json:"longest_worker_disorder,omitempty"`
+	LongestGlobalDisorder int64 `json:"longest_global_disorder,omitempty"`
+	// TimeOrderChecked counts adjacent pairs of IDs, in sorted order within
+	// each chunk, whose embedded timestamps were compared; zero for schemes
+	// without a time decoder. TimeOrderViolations counts the pairs whose
+	// later ID carries the earlier timestamp, i.e. where lexicographic
+	// order disagrees with time order.
+	TimeOrderChecked    int64 `json:"time_order_checked,omitempty"`
+	TimeOrderViolations int64 `json:"time_order_violations,omitempty"`
+	// TruncateBits is the width IDs were truncated to, see
+	// Config.TruncateBits. ExpectedDuplicates and ExpectedDuplicatesStdDev
+	// are then the number of duplicates uniformly random values of that
+	// width would give (the birthday bound) and its standard deviation,
+	// to compare with TotalDuplicates.
+	TruncateBits             int     `json:"truncate_bits,omitempty"`
+	ExpectedDuplicates       float64 `json:"expected_duplicates,omitempty"`
+	ExpectedDuplicatesStdDev float64 `json:"expected_duplicates_stddev,omitempty"`
+	// GenerateErrors counts failed generation attempts (e.g. clock rollback
+	// beyond a scheme's tolerance). Failed attempts are retried.
+	GenerateErrors int64 `json:"generate_errors"`
+	// DuplicatesReport is the NDJSON file listing every colliding ID, or
+	// empty when no duplicates were found.
+	DuplicatesReport string `json:"duplicates_report,omitempty"`
+	// Sources is the number of runs merged together (0 for a single run).
+	Sources int `json:"sources,omitempty"`
+	// CrossSourceDuplicates counts, for every ID produced by more than one
+	// run, the runs beyond the first that produced it: the duplicates left
+	// even if each run deduplicated its own output.
+	CrossSourceDuplicates int64 `json:"cross_source_duplicates,omitempty"`
+	// Latency holds the per-ID generation latency percentiles when latency
+	// sampling was enabled.
+	Latency *Latency `json:"latency,omitempty"`
+	// Environment is the machine and runtime the IDs were generated on. It
+	// is nil for merged runs made in different environments.
+	Environment *Environment `json:"environment,omitempty"`
+	// ManifestPath and OutputDir locate the kept run; they are empty when
+	// the temporary data was removed.
+	ManifestPath string `json:"manifest_path,omitempty"`
+	OutputDir    string `json:"output_dir,omitempty"`
+}
+
+// DuplicatesZScore returns how many standard deviations TotalDuplicates
+// lies from the birthday expectation of a truncated run, or 0 when the run
+// was not truncated.
+func (r Result) DuplicatesZScore() float64 {
+	if r.TruncateBits == 0 || r.ExpectedDuplicatesStdDev == 0 {
+		return 0
+	}
+	return (float64(r.TotalDuplicates) - r.ExpectedDuplicates) / r.ExpectedDuplicatesStdDev
+}
+
+// Uniqueness returns Unique / Generated as a percentage.
+func (r Result) Uniqueness() float64 {
+	if r.Generated == 0 {
+		return 0
+	}
+	return float64(r.Unique) / float64(r.Generated) * 100
 }
 
 type chunkMeta struct {
-	Index         int       `json:"index"`
-	Path          string    `json:"path"`
-	UniqueCount   int64     `json:"unique_count"`
-	OriginalCount int64     `json:"original_count"`
-	Hash          string    `json:"hash"`
-	SizeBytes     int64     `json:"size_bytes"`
-	CreatedAt     time.Time `json:"created_at"`
+	Index           int    `json:"index"`
+	Path            string `json:"path"`
+	UniqueCount     int64  `json:"unique_count"`
+	OriginalCount   int64  `json:"original_count"`
+	Duplicates      int64  `json:"duplicates"`
+	OrderViolations int64  `json:"order_violations"`
+	GenerateErrors  int64  `json:"generate_errors"`
+	// The order analysis fields mirror those of Result for one chunk.
+	GlobalOrderViolations int64     `json:"global_order_violations,omitempty"`
+	LongestWorkerDisorder int64     `json:"longest_worker_disorder,omitempty"`
+	LongestGlobalDisorder int64     `json:"longest_global_disorder,omitempty"`
+	TimeOrderChecked      int64     `json:"time_order_checked,omitempty"`
+	TimeOrderViolations   int64     `json:"time_order_violations,omitempty"`
+	Hash                  string    `json:"hash"`
+	SizeBytes             int64     `json:"size_bytes"`
+	CreatedAt             time.Time `json:"created_at"`
+	// DuplicatesPath is the file listing, in sorted order, every extra
+	// copy dropped from the chunk, so that the manifest stays small when
+	// duplicates are plentiful (truncated runs). It is empty when
+	// Duplicates is 0.
+	DuplicatesPath string `json:"duplicates_path,omitempty"`
+	DuplicatesHash string `json:"duplicates_hash,omitempty"`
+	// Source names the run a chunk came from when the chunks of several
+	// runs are merged together; it is never saved.
+	Source string `json:"-"`
 }
 
 type manifest struct {
-	Scheme           string      `json:"scheme"`
-	Scale            int64       `json:"scale"`
-	ChunkSize        int64       `json:"chunk_size"`
-	ApproxBytesPerID int64       `json:"approx_bytes_per_id"`
+	Scheme           string        `json:"scheme"`
+	Scale            int64         `json:"scale"`
+	ChunkSize        int64         `json:"chunk_size"`
+	ApproxBytesPerID int64         `json:"approx_bytes_per_id"`
+	NodeID           int64         `json:"node_id,omitempty"`
+	Layout           string        `json:"layout,omitempty"`
+	Clock            string        `json:"clock,omitempty"`
+	Environment      *Environment  `json:"environment,omitempty"`
+	LatencySample    int64         `json:"latency_sample,omitempty"`
+	Duration         time.Duration `json:"duration_ns,omitempty"`
+	Rate             float64       `json:"rate,omitempty"`
+	TrackOrder       bool          `json:"track_order,omitempty"`
+	TruncateBits     int           `json:"truncate_bits,omitempty"`
+	// Elapsed is the time spent generating and writing chunks so far, so
+	// that a resumed duration-bounded run only uses what is left of its
+	// budget. Generating is the part of it spent generating IDs.
+	Elapsed    time.Duration `json:"elapsed_ns,omitempty"`
+	Generating time.Duration `json:"generating_ns,omitempty"`
+	// Latency accumulates the sampled latencies of every chunk so far.
+	Latency          *Histogram  `json:"latency,omitempty"`
 	CreatedAt        time.Time   `json:"created_at"`
 	Chunks           []chunkMeta `json:"chunks"`
+	DuplicatesReport string      `json:"duplicates_report,omitempty"`
 }
 
 // Run runs the stress test for the configured schemes and returns results.
 func Run(ctx context.Context, cfg Config) ([]Result, error) {
+	if cfg.ResumeDir != "" {
+		man, err := loadManifest(cfg.ResumeDir)
+		if err != nil {
+			return nil, fmt.Errorf("resume: %w", err)
+		}
+		cfg.Schemes = []string{man.Scheme}
+		cfg.Scale = man.Scale
+		cfg.ChunkSize = man.ChunkSize
+		cfg.ApproxBytesPerID = man.ApproxBytesPerID
+		cfg.NodeID = man.NodeID
+		cfg.Layout = man.Layout
+		cfg.Clock = man.Clock
+		cfg.LatencySample = man.LatencySample
+		cfg.Duration = man.Duration
+		cfg.Rate = man.Rate
+		cfg.TrackOrder = man.TrackOrder
+		cfg.TruncateBits = man.TruncateBits
+	}
 	if len(cfg.Schemes) == 0 {
 		cfg.Schemes = []string{"nanoid16", "ulid", "ksuid"}
 	}
-	if cfg.Scale <= 0 {
-		return nil, fmt.Errorf("scale must be > 0")
+	schemes, err := resolveSchemes(cfg.Schemes)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Schemes = schemes
+	if cfg.Duration < 0 || cfg.Rate < 0 {
+		return nil, fmt.Errorf("duration and rate must not be negative")
 	}
-	if cfg.ChunkSize <= 0 || cfg.ChunkSize > cfg.Scale {
-		cfg.ChunkSize = minInt64(cfg.Scale, 1_000_000)
+	if cfg.TruncateBits != 0 {
+		if cfg.TruncateBits < 1 || cfg.TruncateBits > 64 {
+			return nil, fmt.Errorf("truncate bits must be in [1, 64], got %d", cfg.TruncateBits)
+		}
+		if cfg.TrackOrder {
+			return nil, fmt.Errorf("order tracking does not apply to truncated IDs")
+		}
+	}
+	switch {
+	case cfg.Scale > 0:
+		if cfg.ChunkSize <= 0 || cfg.ChunkSize > cfg.Scale {
+			cfg.ChunkSize = minInt64(cfg.Scale, 1_000_000)
+		}
+	case cfg.Duration > 0:
+		cfg.Scale = 0
+		if cfg.ChunkSize <= 0 {
+			cfg.ChunkSize = 1_000_000
+		}
+	default:
+		return nil, fmt.Errorf("scale must be > 0 unless a duration is given")
 	}
 	if cfg.Workers <= 0 {
 		cfg.Workers = runtime.NumCPU()
@@ -98,6 +293,9 @@ func Run(ctx context.Context, cfg Config) ([]Result, error) {
 	if cfg.DiskSafetyFactor <= 0 {
 		cfg.DiskSafetyFactor = 1.25
 	}
+	if err := waitUntil(ctx, cfg.StartAt); err != nil {
+		return nil, err
+	}
 
 	results := make([]Result, 0, len(cfg.Schemes))
 	for _, scheme := range cfg.Schemes {
@@ -118,60 +316,119 @@ func Run(ctx context.Context, cfg Config) ([]Result, error) {
 	return results, nil
 }
 
-func runScheme(ctx context.Context, scheme string, cfg Config) (Result, error) {
-	gen, err := generatorFor(scheme)
+func runScheme(ctx context.Context, scheme string, cfg Config) (res Result, err error) {
+	gen, err := newGenerator(scheme, cfg)
 	if err != nil {
 		return Result{}, err
 	}
 
-	baseDir := cfg.TempDir
-	if baseDir == "" {
-		// 默认使用当前工作目录下的 tmp 目录
-		cwd, err := os.Getwd()
+	var (
+		tempDir string
+		man     *manifest
+	)
+	if cfg.ResumeDir != "" {
+		tempDir = cfg.ResumeDir
+		man, err = loadManifest(tempDir)
 		if err != nil {
-			return Result{}, fmt.Errorf("get current directory: %w", err)
+			return Result{}, fmt.Errorf("resume: %w", err)
 		}
-		baseDir = filepath.Join(cwd, "tmp")
-		// 确保 tmp 目录存在
-		if err := os.MkdirAll(baseDir, 0o755); err != nil {
-			return Result{}, fmt.Errorf("create tmp directory: %w", err)
+		if err := verifyChunks(man); err != nil {
+			return Result{}, fmt.Errorf("resume: %w", err)
+		}
+	} else {
+		tempDir, err = createRunDir(cfg.TempDir, scheme)
+		if err != nil {
+			return Result{}, err
 		}
+		man = &manifest{
+			Scheme:           scheme,
+			Scale:            cfg.Scale,
+			ChunkSize:        cfg.ChunkSize,
+			ApproxBytesPerID: cfg.ApproxBytesPerID,
+			NodeID:           cfg.NodeID,
+			Layout:           cfg.Layout,
+			Clock:            cfg.Clock,
+			LatencySample:    cfg.LatencySample,
+			Duration:         cfg.Duration,
+			Rate:             cfg.Rate,
+			TrackOrder:       cfg.TrackOrder,
+			TruncateBits:     cfg.TruncateBits,
+			CreatedAt:        time.Now(),
+		}
+		env := CurrentEnvironment()
+		man.Environment = &env
 	}
-	tempDir, err := os.MkdirTemp(baseDir, fmt.Sprintf("uidstress-%s-", scheme))
-	if err != nil {
-		return Result{}, fmt.Errorf("create temp dir: %w", err)
+	defer func() {
+		switch {
+		case err == nil && !cfg.KeepTempData && cfg.ResumeDir == "":
+			os.RemoveAll(tempDir)
+			res.ManifestPath, res.OutputDir = "", ""
+		case err != nil && len(man.Chunks) == 0 && cfg.ResumeDir == "":
+			os.RemoveAll(tempDir)
+		case err != nil:
+			// Keep completed chunks so the run can be continued.
+			err = fmt.Errorf("%w (partial run kept in %s, continue with -resume %s)", err, tempDir, tempDir)
+		}
+	}()
+
+	var (
+		totalGenerated int64
+		chunkIndex     = len(man.Chunks)
+	)
+	for _, ch := range man.Chunks {
+		totalGenerated += ch.OriginalCount
 	}
-	if !cfg.KeepTempData {
-		defer os.RemoveAll(tempDir)
+	if cfg.Verbose && chunkIndex > 0 {
+		fmt.Printf("[%s] resuming at chunk %d with %d IDs generated\n", scheme, chunkIndex, totalGenerated)
 	}
 
-	estimatedBytes := cfg.Scale * cfg.ApproxBytesPerID
-	if err := ensureDisk(tempDir, estimatedBytes, cfg.DiskSafetyFactor); err != nil {
-		return Result{}, err
+	// remaining is the number of IDs still to generate, or -1 when only
+	// the duration bounds the run.
+	remaining := func() int64 {
+		if cfg.Scale <= 0 {
+			return -1
+		}
+		return cfg.Scale - totalGenerated
 	}
-
-	man := &manifest{
-		Scheme:           scheme,
-		Scale:            cfg.Scale,
-		ChunkSize:        cfg.ChunkSize,
-		ApproxBytesPerID: cfg.ApproxBytesPerID,
-		CreatedAt:        time.Now(),
+	decoder, _ := gen.(tools.TimeDecoder)
+	var opts chunkOptions
+	if cfg.TruncateBits > 0 {
+		if opts.truncate, err = truncator(gen, cfg.TruncateBits); err != nil {
+			return Result{}, err
+		}
+		// Truncated values carry no timestamp.
+		decoder = nil
+	}
+	opts.sample = cfg.LatencySample
+	opts.trackOrder = cfg.TrackOrder
+	if cfg.Rate > 0 {
+		opts.bucket = newTokenBucket(cfg.Rate)
+	}
+	if cfg.Duration > 0 {
+		opts.deadline = time.Now().Add(cfg.Duration - man.Elapsed)
 	}
 
-	var (
-		totalGenerated int64
-		totalUniqueSum int64
-		chunkIndex     int
-	)
+	// Without a scale the disk estimate comes from the rate, if any.
+	estimated := remaining()
+	if estimated < 0 {
+		estimated = int64(cfg.Rate * (cfg.Duration - man.Elapsed).Seconds())
+	}
+	if err := ensureDisk(tempDir, estimated*cfg.ApproxBytesPerID, cfg.DiskSafetyFactor); err != nil {
+		return Result{}, err
+	}
 
-	for totalGenerated < cfg.Scale {
+	for remaining() != 0 && (opts.deadline.IsZero() || time.Now().Before(opts.deadline)) {
 		select {
 		case <-ctx.Done():
 			return Result{}, ctx.Err()
 		default:
 		}
 
-		chunkTarget := minInt64(cfg.ChunkSize, cfg.Scale-totalGenerated)
+		chunkStart := time.Now()
+		chunkTarget := cfg.ChunkSize
+		if left := remaining(); left > 0 {
+			chunkTarget = minInt64(chunkTarget, left)
+		}
 		if err := ensureMemory(cfg, chunkTarget); err != nil {
 			return Result{}, err
 		}
@@ -179,14 +436,30 @@ func runScheme(ctx context.Context, scheme string, cfg Config) (Result, error) {
 			return Result{}, fmt.Errorf("chunk size %d exceeds supported slice capacity", chunkTarget)
 		}
 
-		chunkIDs := make([]string, 0, int(chunkTarget))
-		for int64(len(chunkIDs)) < chunkTarget {
-			chunkIDs = append(chunkIDs, gen())
+		genStart := time.Now()
+		chunkIDs, stats, err := generateChunk(ctx, gen, int(chunkTarget), cfg.Workers, opts)
+		if err != nil {
+			return Result{}, err
+		}
+		generating := time.Since(genStart)
+		// The deadline may cut the chunk short.
+		chunkTarget = int64(len(chunkIDs))
+		if chunkTarget == 0 {
+			break
+		}
+		if stats.latency != nil {
+			if man.Latency == nil {
+				man.Latency = &Histogram{}
+			}
+			man.Latency.Merge(stats.latency)
 		}
 
 		sort.Strings(chunkIDs)
-		unique := dedupeSorted(chunkIDs)
+		unique, dropped := dedupeSorted(chunkIDs)
 		chunkHash := hashStrings(unique)
+		if cfg.TrackOrder && decoder != nil {
+			stats.timeChecked, stats.timeInversions = timeOrderViolations(unique, decoder)
+		}
 
 		chunkPath := filepath.Join(tempDir, fmt.Sprintf("%s-chunk-%05d.dat", scheme, chunkIndex))
 		if err := writeChunkFile(chunkPath, unique); err != nil {
@@ -203,27 +476,58 @@ func runScheme(ctx context.Context, scheme string, cfg Config) (Result, error) {
 		if err != nil {
 			return Result{}, err
 		}
+		var dupPath, dupHash string
+		if len(dropped) > 0 {
+			dupPath = filepath.Join(tempDir, fmt.Sprintf("%s-chunk-%05d.dup", scheme, chunkIndex))
+			if err := writeChunkFile(dupPath, dropped); err != nil {
+				return Result{}, err
+			}
+			if dupHash, err = hashFile(dupPath); err != nil {
+				return Result{}, err
+			}
+		}
 
 		meta := chunkMeta{
-			Index:         chunkIndex,
-			Path:          chunkPath,
-			UniqueCount:   int64(len(unique)),
-			OriginalCount: chunkTarget,
-			Hash:          chunkHash,
-			SizeBytes:     info.Size(),
-			CreatedAt:     time.Now(),
+			Index:                 chunkIndex,
+			Path:                  chunkPath,
+			UniqueCount:           int64(len(unique)),
+			OriginalCount:         chunkTarget,
+			Duplicates:            chunkTarget - int64(len(unique)),
+			DuplicatesPath:        dupPath,
+			DuplicatesHash:        dupHash,
+			OrderViolations:       stats.inversions,
+			GenerateErrors:        stats.errors,
+			GlobalOrderViolations: stats.globalInversions,
+			LongestWorkerDisorder: stats.longestDisorder,
+			LongestGlobalDisorder: stats.longestGlobalDisorder,
+			TimeOrderChecked:      stats.timeChecked,
+			TimeOrderViolations:   stats.timeInversions,
+
+			Hash:      chunkHash,
+			SizeBytes: info.Size(),
+			CreatedAt: time.Now(),
 		}
 		man.Chunks = append(man.Chunks, meta)
+		man.Elapsed += time.Since(chunkStart)
+		man.Generating += generating
 		if err := saveManifest(tempDir, man); err != nil {
 			return Result{}, err
 		}
 
+		before := totalGenerated
 		totalGenerated += chunkTarget
-		totalUniqueSum += int64(len(unique))
 		chunkIndex++
 
-		if cfg.Verbose && totalGenerated%cfg.LogInterval == 0 {
-			fmt.Printf("[%s] generated %d / %d IDs\n", scheme, totalGenerated, cfg.Scale)
+		if cfg.Verbose && meta.Duplicates > 0 {
+			fmt.Printf("[%s] chunk %d contains %d duplicate IDs\n", scheme, meta.Index, meta.Duplicates)
+		}
+
+		if cfg.Verbose && before/cfg.LogInterval != totalGenerated/cfg.LogInterval {
+			if cfg.Scale > 0 {
+				fmt.Printf("[%s] generated %d / %d IDs\n", scheme, totalGenerated, cfg.Scale)
+			} else {
+				fmt.Printf("[%s] generated %d IDs in %s\n", scheme, totalGenerated, man.Elapsed.Round(time.Millisecond))
+			}
 		}
 	}
 
@@ -231,40 +535,472 @@ func runScheme(ctx context.Context, scheme string, cfg Config) (Result, error) {
 		return Result{}, err
 	}
 
-	unique, duplicates, err := mergeChunks(ctx, man, cfg.Verbose, cfg.LogInterval)
+	// The report lives next to the run directory so it survives cleanup.
+	res, err = mergeRun(ctx, man, filepath.Clean(tempDir)+"-duplicates.ndjson", decoder, cfg)
 	if err != nil {
 		return Result{}, err
 	}
-	if totalUniqueSum != unique+duplicates {
+	if res.DuplicatesReport != "" {
+		man.DuplicatesReport = res.DuplicatesReport
+		if err := saveManifest(tempDir, man); err != nil {
+			return Result{}, err
+		}
+	}
+	res.Sortable = gen.Meta().Sortable && cfg.TruncateBits == 0
+	res.ManifestPath = filepath.Join(tempDir, "manifest.json")
+	res.OutputDir = tempDir
+	return res, nil
+}
+
+// mergeRun merges the chunks of man, writing every colliding ID to
+// reportPath, and summarizes the run from its chunk metadata.
+func mergeRun(ctx context.Context, man *manifest, reportPath string, decoder tools.TimeDecoder, cfg Config) (Result, error) {
+	res := Result{Scheme: man.Scheme, Chunks: len(man.Chunks), Environment: man.Environment, OrderTracked: man.TrackOrder}
+	sources := make(map[string]bool)
+	for _, ch := range man.Chunks {
+		res.Generated += ch.OriginalCount
+		res.ChunkUnique += ch.UniqueCount
+		res.IntraChunkDuplicates += ch.Duplicates
+		res.OrderViolations += ch.OrderViolations
+		res.GenerateErrors += ch.GenerateErrors
+		res.GlobalOrderViolations += ch.GlobalOrderViolations
+		res.LongestWorkerDisorder = max(res.LongestWorkerDisorder, ch.LongestWorkerDisorder)
+		res.LongestGlobalDisorder = max(res.LongestGlobalDisorder, ch.LongestGlobalDisorder)
+		res.TimeOrderChecked += ch.TimeOrderChecked
+		res.TimeOrderViolations += ch.TimeOrderViolations
+		if ch.Source != "" {
+			sources[ch.Source] = true
+		}
+	}
+	res.Sources = len(sources)
+
+	report := newDuplicateReport(reportPath, decoder)
+	unique, duplicates, err := mergeChunks(ctx, man, report, cfg.Verbose, cfg.LogInterval)
+	if cerr := report.close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if report.records > 0 {
+		res.DuplicatesReport = reportPath
+	}
+	if res.ChunkUnique != unique+duplicates {
 		return Result{}, fmt.Errorf("inconsistent counts: chunk unique sum=%d, merged unique=%d, duplicates=%d",
-			totalUniqueSum, unique, duplicates)
-	}
-
-	return Result{
-		Scheme:       scheme,
-		Chunks:       len(man.Chunks),
-		Generated:    totalGenerated,
-		ChunkUnique:  totalUniqueSum,
-		Unique:       unique,
-		Duplicates:   duplicates,
-		ManifestPath: filepath.Join(tempDir, "manifest.json"),
-		OutputDir:    tempDir,
-	}, nil
-}
-
-func generatorFor(name string) (func() string, error) {
-	switch strings.ToLower(name) {
-	case "nanoid16", "nanoid":
-		return func() string { return tools.GetNanoIdBy(16) }, nil
-	case "ulid":
-		return tools.GenerateULID, nil
-	case "ksuid":
-		return tools.GenerateKSUID, nil
-	case "customuid", "custom":
-		return tools.GenerateCustomUID, nil
-	default:
-		return nil, fmt.Errorf("unknown scheme %q", name)
+			res.ChunkUnique, unique, duplicates)
+	}
+	res.Unique = unique
+	res.Duplicates = duplicates
+	res.TotalDuplicates = res.Generated - unique
+	res.CrossSourceDuplicates = report.crossSource
+	res.GenerateTime = man.Generating
+	res.Latency = man.Latency.Latency()
+	if man.TruncateBits > 0 {
+		res.TruncateBits = man.TruncateBits
+		res.ExpectedDuplicates, res.ExpectedDuplicatesStdDev = birthdayDuplicates(float64(res.Generated), math.Ldexp(1, man.TruncateBits))
+	}
+	return res, nil
+}
+
+// combineManifests concatenates the chunks of several runs of the same
+// scheme into one manifest, tagging each chunk with the name of the run it
+// came from. Chunks keep their index within their own run. The runs must
+// agree on layout, clock and truncation: IDs are decoded with a single
+// layout, and mixing the others would compare unlike things.
+func combineManifests(mans []*manifest, sources []string) (*manifest, error) {
+	if len(mans) == 0 {
+		return nil, errors.New("no manifests to combine")
+	}
+	combined := &manifest{
+		Scheme:           mans[0].Scheme,
+		ApproxBytesPerID: mans[0].ApproxBytesPerID,
+		Layout:           mans[0].Layout,
+		Clock:            mans[0].Clock,
+		Environment:      mans[0].Environment,
+		TrackOrder:       true,
+		TruncateBits:     mans[0].TruncateBits,
+		CreatedAt:        time.Now(),
+	}
+	for i, man := range mans {
+		switch {
+		case man.Scheme != combined.Scheme:
+			return nil, fmt.Errorf("cannot merge %s run %s with %s runs", man.Scheme, sources[i], combined.Scheme)
+		case man.Layout != combined.Layout:
+			return nil, fmt.Errorf("cannot merge run %s with layout %q with runs with layout %q",
+				sources[i], man.Layout, combined.Layout)
+		case man.Clock != combined.Clock:
+			return nil, fmt.Errorf("cannot merge run %s with clock %q with runs with clock %q",
+				sources[i], man.Clock, combined.Clock)
+		case man.TruncateBits != combined.TruncateBits:
+			return nil, fmt.Errorf("cannot merge run %s truncated to %d bits with runs truncated to %d bits",
+				sources[i], man.TruncateBits, combined.TruncateBits)
+		}
+		combined.TrackOrder = combined.TrackOrder && man.TrackOrder
+		if combined.Environment != nil && (man.Environment == nil || *man.Environment != *combined.Environment) {
+			combined.Environment = nil
+		}
+		combined.Scale += man.Scale
+		combined.Generating += man.Generating
+		if man.Latency != nil {
+			if combined.Latency == nil {
+				combined.Latency = &Histogram{}
+			}
+			combined.Latency.Merge(man.Latency)
+		}
+		for _, ch := range man.Chunks {
+			ch.Source = sources[i]
+			combined.Chunks = append(combined.Chunks, ch)
+		}
+	}
+	return combined, nil
+}
+
+// waitUntil blocks until t, returning early if ctx is cancelled. A zero t
+// returns immediately.
+func waitUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if t.IsZero() || d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newGenerator looks up scheme in the registry and applies the per-run
+// options from cfg that the scheme supports.
+func newGenerator(scheme string, cfg Config) (tools.Generator, error) {
+	gen, err := tools.Lookup(scheme)
+	if err != nil {
+		return nil, err
+	}
+	if lc, ok := gen.(tools.LayoutConfigurable); ok && cfg.Layout != "" {
+		if gen, err = lc.WithLayout(cfg.Layout); err != nil {
+			return nil, err
+		}
+	}
+	if nc, ok := gen.(tools.NodeConfigurable); ok && cfg.NodeID != 0 {
+		if gen, err = nc.WithNodeID(cfg.NodeID); err != nil {
+			return nil, err
+		}
+	}
+	clock, err := parseClock(cfg.Clock)
+	if err != nil {
+		return nil, err
+	}
+	if cc, ok := gen.(tools.ClockConfigurable); ok && clock != nil {
+		if gen, err = cc.WithClock(clock); err != nil {
+			return nil, err
+		}
+	}
+	return gen, nil
+}
+
+// createRunDir creates a fresh run directory for scheme under baseDir,
+// defaulting baseDir to ./tmp.
+func createRunDir(baseDir, scheme string) (string, error) {
+	if baseDir == "" {
+		// 默认使用当前工作目录下的 tmp 目录
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("get current directory: %w", err)
+		}
+		baseDir = filepath.Join(cwd, "tmp")
+		// 确保 tmp 目录存在
+		if err := os.MkdirAll(baseDir, 0o755); err != nil {
+			return "", fmt.Errorf("create tmp directory: %w", err)
+		}
+	}
+	tempDir, err := os.MkdirTemp(baseDir, fmt.Sprintf("uidstress-%s-", scheme))
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	return tempDir, nil
+}
+
+// generateStallTimeout bounds how long a worker keeps retrying a generator
+// that returns nothing but errors.
+const generateStallTimeout = 10 * time.Second
+
+// chunkOptions bounds and instruments chunk generation.
+type chunkOptions struct {
+	// sample times every sample-th ID of each worker (0 disables timing).
+	sample int64
+	// bucket, when set, limits the combined rate of all workers.
+	bucket *tokenBucket
+	// deadline, when set, stops generation early.
+	deadline time.Time
+	// trackOrder numbers IDs across workers in the order calls return,
+	// using counter, which generateChunk creates for each chunk.
+	trackOrder bool
+	counter    *atomic.Int64
+	// truncate, when set, replaces each ID as soon as it is generated.
+	truncate func(id string) (string, error)
+}
+
+// chunkStats collects per-chunk generation statistics.
+type chunkStats struct {
+	inversions int64      // IDs sorting before the same worker's previous ID
+	errors     int64      // failed generation attempts
+	latency    *Histogram // sampled latencies, nil when sampling is off
+	generated  int        // IDs written to the worker's range
+
+	// Order analysis, filled when chunkOptions.trackOrder is set.
+	longestDisorder       int64 // longest run below a worker's maximum so far
+	globalInversions      int64 // IDs sorting before the previously returned ID
+	longestGlobalDisorder int64 // longest run below the chunk's maximum so far
+	timeChecked           int64 // sorted adjacent pairs with decodable times
+	timeInversions        int64 // sorted adjacent pairs going back in time
+}
+
+// generateChunk generates up to n IDs, splitting the work evenly across
+// workers goroutines that call the generator concurrently. Each worker fills
+// its own contiguous range of the returned slice, which is shorter than n
+// when opts.deadline passes first. Generators implementing
+// tools.CheckedGenerator are retried with a short backoff on error. When
+// opts.sample > 0 every worker times every sample-th ID into its own
+// histogram and the histograms are merged into the returned stats.
+func generateChunk(ctx context.Context, gen tools.Generator, n, workers int, opts chunkOptions) ([]string, chunkStats, error) {
+	ids := make([]string, n)
+	var seqs []int64
+	if opts.trackOrder {
+		seqs = make([]int64, n)
+		opts.counter = new(atomic.Int64)
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		stats, err := generateRange(ctx, gen, ids, seqs, opts)
+		ids = ids[:stats.generated]
+		if opts.trackOrder {
+			stats.globalInversions, stats.longestGlobalDisorder = globalOrder(ids, seqs[:stats.generated])
+		}
+		return ids, stats, err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		total    chunkStats
+		firstErr error
+		ranges   = make([][2]int, workers)
+	)
+	per := n / workers
+	extra := n % workers
+	start := 0
+	for w := 0; w < workers; w++ {
+		end := start + per
+		if w < extra {
+			end++
+		}
+		var partSeqs []int64
+		if seqs != nil {
+			partSeqs = seqs[start:end]
+		}
+		wg.Add(1)
+		go func(w, start int, part []string, partSeqs []int64) {
+			defer wg.Done()
+			stats, err := generateRange(ctx, gen, part, partSeqs, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			ranges[w] = [2]int{start, start + stats.generated}
+			total.inversions += stats.inversions
+			total.errors += stats.errors
+			total.generated += stats.generated
+			total.longestDisorder = max(total.longestDisorder, stats.longestDisorder)
+			if stats.latency != nil {
+				if total.latency == nil {
+					total.latency = stats.latency
+				} else {
+					total.latency.Merge(stats.latency)
+				}
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(w, start, ids[start:end], partSeqs)
+		start = end
+	}
+	wg.Wait()
+	if total.generated < n {
+		// Close the gaps left by workers stopped at the deadline.
+		filled := 0
+		for _, r := range ranges {
+			copy(ids[filled:], ids[r[0]:r[1]])
+			if seqs != nil {
+				copy(seqs[filled:], seqs[r[0]:r[1]])
+			}
+			filled += r[1] - r[0]
+		}
+		ids = ids[:filled]
+	}
+	if opts.trackOrder {
+		total.globalInversions, total.longestGlobalDisorder = globalOrder(ids, seqs[:len(ids)])
+	}
+	return ids, total, firstErr
+}
+
+// generateRange fills part with IDs from gen in generation order, stopping
+// early at opts.deadline. The latency of a sampled ID includes any failed
+// attempts and backoff before it was generated, but not rate limiting.
+// With opts.trackOrder, seqs[i] receives the chunk-wide sequence number of
+// part[i], taken when the call returned.
+func generateRange(ctx context.Context, gen tools.Generator, part []string, seqs []int64, opts chunkOptions) (chunkStats, error) {
+	var (
+		stats    chunkStats
+		maxID    string
+		disorder int64
+	)
+	if opts.sample > 0 {
+		stats.latency = &Histogram{}
+	}
+	checked, _ := gen.(tools.CheckedGenerator)
+	for i := range part {
+		if opts.bucket != nil {
+			if err := opts.bucket.wait(ctx); err != nil {
+				return stats, err
+			}
+		}
+		// Reading the clock for every ID would slow down unlimited runs;
+		// rate-limited ones have just waited far longer than a clock read.
+		if !opts.deadline.IsZero() && (opts.bucket != nil || i%64 == 0) && !time.Now().Before(opts.deadline) {
+			break
+		}
+		var (
+			id    string
+			start time.Time
+		)
+		timed := opts.sample > 0 && int64(i)%opts.sample == 0
+		if timed {
+			start = time.Now()
+		}
+		if checked == nil {
+			id = gen.Generate()
+		} else {
+			var failingSince time.Time
+			for {
+				var err error
+				if id, err = checked.TryGenerate(); err == nil {
+					break
+				}
+				stats.errors++
+				if failingSince.IsZero() {
+					failingSince = time.Now()
+				} else if time.Since(failingSince) > generateStallTimeout {
+					return stats, fmt.Errorf("generator %s failing for %s: %w", gen.Name(), generateStallTimeout, err)
+				}
+				if ctx.Err() != nil {
+					return stats, ctx.Err()
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		if timed {
+			stats.latency.Record(int64(time.Since(start)))
+		}
+		if opts.truncate != nil {
+			var err error
+			if id, err = opts.truncate(id); err != nil {
+				return stats, err
+			}
+		}
+		if opts.trackOrder {
+			seqs[i] = opts.counter.Add(1) - 1
+			if i > 0 && id < maxID {
+				disorder++
+				stats.longestDisorder = max(stats.longestDisorder, disorder)
+			} else {
+				disorder, maxID = 0, id
+			}
+		}
+		if i > 0 && id < part[i-1] {
+			stats.inversions++
+		}
+		part[i] = id
+		stats.generated++
+	}
+	return stats, nil
+}
+
+// globalOrder replays ids in the order their calls returned, given by
+// seqs, and returns the number of IDs sorting before their predecessor and
+// the longest run of IDs sorting before the largest ID seen so far.
+func globalOrder(ids []string, seqs []int64) (inversions, longest int64) {
+	order := make([]int, len(ids))
+	for i, seq := range seqs {
+		order[seq] = i
+	}
+	var (
+		maxID    string
+		disorder int64
+	)
+	for k, i := range order {
+		id := ids[i]
+		if k > 0 && id < ids[order[k-1]] {
+			inversions++
+		}
+		if k > 0 && id < maxID {
+			disorder++
+			longest = max(longest, disorder)
+		} else {
+			disorder, maxID = 0, id
+		}
+	}
+	return inversions, longest
+}
+
+// timeOrderViolations walks sorted IDs and counts adjacent pairs whose
+// embedded timestamps go backwards. IDs that fail to decode are skipped.
+func timeOrderViolations(sorted []string, decoder tools.TimeDecoder) (checked, violations int64) {
+	var prev time.Time
+	for _, id := range sorted {
+		t, err := decoder.DecodeTime(id)
+		if err != nil {
+			prev = time.Time{}
+			continue
+		}
+		if !prev.IsZero() {
+			checked++
+			if t.Before(prev) {
+				violations++
+			}
+		}
+		prev = t
 	}
+	return checked, violations
+}
+
+// resolveSchemes maps scheme names and aliases to their canonical registry
+// names, expanding "all" to every registered scheme and dropping repeats.
+func resolveSchemes(names []string) ([]string, error) {
+	result := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	for _, name := range names {
+		if strings.EqualFold(strings.TrimSpace(name), "all") {
+			for _, n := range tools.Names() {
+				add(n)
+			}
+			continue
+		}
+		gen, err := tools.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		add(gen.Name())
+	}
+	return result, nil
 }
 
 func ensureMemory(cfg Config, chunkTarget int64) error {
@@ -367,6 +1103,41 @@ func saveManifest(dir string, man *manifest) error {
 	return os.WriteFile(filepath.Join(dir, "manifest.json.sha256"), []byte(hex.EncodeToString(sum[:])), 0o644)
 }
 
+// loadManifest reads dir/manifest.json, checking it against the stored
+// manifest.json.sha256 when present. Chunk paths are rebased onto dir so a
+// run directory can be moved or copied before it is loaded.
+func loadManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	sum, err := os.ReadFile(filepath.Join(dir, "manifest.json.sha256"))
+	switch {
+	case err == nil:
+		got := sha256.Sum256(data)
+		if hex.EncodeToString(got[:]) != strings.TrimSpace(string(sum)) {
+			return nil, fmt.Errorf("manifest %s checksum mismatch", dir)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	var man manifest
+	if err := json.Unmarshal(data, &man); err != nil {
+		return nil, fmt.Errorf("decode manifest %s: %w", dir, err)
+	}
+	for i := range man.Chunks {
+		if man.Chunks[i].Index != i {
+			return nil, fmt.Errorf("manifest %s: chunk %d has index %d", dir, i, man.Chunks[i].Index)
+		}
+		man.Chunks[i].Path = filepath.Join(dir, filepath.Base(man.Chunks[i].Path))
+		if man.Chunks[i].DuplicatesPath != "" {
+			man.Chunks[i].DuplicatesPath = filepath.Join(dir, filepath.Base(man.Chunks[i].DuplicatesPath))
+		}
+	}
+	return &man, nil
+}
+
 func verifyChunks(man *manifest) error {
 	for _, ch := range man.Chunks {
 		hash, err := hashFile(ch.Path)
@@ -376,6 +1147,15 @@ func verifyChunks(man *manifest) error {
 		if hash != ch.Hash {
 			return fmt.Errorf("chunk %s hash mismatch, expected %s got %s", ch.Path, ch.Hash, hash)
 		}
+		if ch.DuplicatesPath == "" {
+			continue
+		}
+		if hash, err = hashFile(ch.DuplicatesPath); err != nil {
+			return fmt.Errorf("hash chunk duplicates %s: %w", ch.DuplicatesPath, err)
+		}
+		if hash != ch.DuplicatesHash {
+			return fmt.Errorf("chunk duplicates %s hash mismatch, expected %s got %s", ch.DuplicatesPath, ch.DuplicatesHash, hash)
+		}
 	}
 	return nil
 }
@@ -385,11 +1165,30 @@ type chunkReader struct {
 	file   *os.File
 	reader *bufio.Scanner
 	value  string
+	line   int64 // 1-based line number of value
 	eof    bool
+	// dups reads the chunk's dropped copies alongside it; nil when the
+	// chunk had none.
+	dups *chunkReader
 }
 
 func newChunkReader(meta chunkMeta) (*chunkReader, error) {
-	f, err := os.Open(meta.Path)
+	cr, err := openChunkFile(meta, meta.Path)
+	if err != nil {
+		return nil, err
+	}
+	if meta.DuplicatesPath != "" {
+		if cr.dups, err = openChunkFile(meta, meta.DuplicatesPath); err != nil {
+			cr.close()
+			return nil, err
+		}
+	}
+	return cr, nil
+}
+
+// openChunkFile opens one sorted file of IDs and reads its first line.
+func openChunkFile(meta chunkMeta, path string) (*chunkReader, error) {
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -421,10 +1220,30 @@ func (c *chunkReader) advance() error {
 		return nil
 	}
 	c.value = c.reader.Text()
+	c.line++
 	return nil
 }
 
+// dropped returns the number of extra copies of the current value that
+// were dropped from the chunk. Both files are sorted, so the duplicates
+// file is consumed in step with the chunk.
+func (c *chunkReader) dropped() (int, error) {
+	n := 0
+	for c.dups != nil && !c.dups.eof && c.dups.value <= c.value {
+		if c.dups.value == c.value {
+			n++
+		}
+		if err := c.dups.advance(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
 func (c *chunkReader) close() error {
+	if c.dups != nil {
+		c.dups.close()
+	}
 	return c.file.Close()
 }
 
@@ -451,7 +1270,11 @@ func (h *mergeHeap) Pop() any {
 	return item
 }
 
-func mergeChunks(ctx context.Context, man *manifest, verbose bool, logInterval int64) (int64, int64, error) {
+// mergeChunks k-way merges the sorted chunk files of man and counts unique
+// and cross-chunk duplicate IDs. Every ID seen more than once, including
+// copies dropped inside a chunk (read from the chunk's duplicates file), is
+// written to report when it is non-nil.
+func mergeChunks(ctx context.Context, man *manifest, report *duplicateReport, verbose bool, logInterval int64) (int64, int64, error) {
 	if len(man.Chunks) == 0 {
 		return 0, 0, errors.New("manifest contains no chunks")
 	}
@@ -492,6 +1315,7 @@ func mergeChunks(ctx context.Context, man *manifest, verbose bool, logInterval i
 		unique     int64
 		duplicates int64
 		processed  int64
+		group      []occurrence // copies of prevValue seen so far
 	)
 
 	for len(h) > 0 {
@@ -502,14 +1326,26 @@ func mergeChunks(ctx context.Context, man *manifest, verbose bool, logInterval i
 		}
 
 		entry := heap.Pop(&h).(*heapEntry)
+		occ := occurrence{Source: entry.reader.meta.Source, Chunk: entry.reader.meta.Index, Line: entry.reader.line}
+		dropped, err := entry.reader.dropped()
+		if err != nil {
+			return 0, 0, err
+		}
 
 		if hasPrev && entry.value == prevValue {
 			duplicates++
 		} else {
+			if err := report.add(prevValue, group); err != nil {
+				return 0, 0, err
+			}
+			group = group[:0]
 			unique++
 			prevValue = entry.value
 			hasPrev = true
 		}
+		for i := 0; i <= dropped; i++ {
+			group = append(group, occ)
+		}
 		processed++
 
 		if verbose && logInterval > 0 && processed%logInterval == 0 {
@@ -524,14 +1360,20 @@ func mergeChunks(ctx context.Context, man *manifest, verbose bool, logInterval i
 			heap.Push(&h, &heapEntry{value: entry.reader.value, reader: entry.reader})
 		}
 	}
+	if err := report.add(prevValue, group); err != nil {
+		return 0, 0, err
+	}
 
 	return unique, duplicates, nil
 }
 
-func dedupeSorted(values []string) []string {
+// dedupeSorted removes repeated values from the sorted slice in place. It
+// returns the unique values and one entry for every extra copy dropped.
+func dedupeSorted(values []string) ([]string, []string) {
 	if len(values) == 0 {
-		return values[:0]
+		return values[:0], nil
 	}
+	var dropped []string
 	writeIdx := 1
 	prev := values[0]
 	for i := 1; i < len(values); i++ {
@@ -539,9 +1381,11 @@ func dedupeSorted(values []string) []string {
 			values[writeIdx] = values[i]
 			prev = values[i]
 			writeIdx++
+		} else {
+			dropped = append(dropped, values[i])
 		}
 	}
-	return values[:writeIdx]
+	return values[:writeIdx], dropped
 }
 
 func minInt64(a, b int64) int64 {
@@ -0,0 +1,335 @@
+package uidstress
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"id-tester/internal/tools"
+)
+
+// scriptedIDs is the generator behind the "testseq" scheme: it returns
+// ids in order, starting over at the end, so a test knows which IDs
+// collide and, with one worker, in which chunk.
+var scriptedIDs struct {
+	sync.Mutex
+	ids  []string
+	next int
+}
+
+func init() {
+	tools.MustRegister(tools.NewGenerator("testseq", tools.Meta{Charset: "abcdefghijklmnopqrstuvwxyz"}, func() string {
+		scriptedIDs.Lock()
+		defer scriptedIDs.Unlock()
+		id := scriptedIDs.ids[scriptedIDs.next%len(scriptedIDs.ids)]
+		scriptedIDs.next++
+		return id
+	}))
+}
+
+// script makes testseq return ids from position next on and returns the
+// number of IDs generated so far.
+func script(ids []string, next int) func() int {
+	scriptedIDs.Lock()
+	defer scriptedIDs.Unlock()
+	scriptedIDs.ids, scriptedIDs.next = ids, next
+	return func() int {
+		scriptedIDs.Lock()
+		defer scriptedIDs.Unlock()
+		return scriptedIDs.next
+	}
+}
+
+// runScripted runs testseq over ids in chunks of chunk IDs with a single
+// worker and keeps the run directory.
+func runScripted(t *testing.T, ids []string, chunk int64) Result {
+	t.Helper()
+	script(ids, 0)
+	results, err := Run(context.Background(), Config{
+		Schemes:      []string{"testseq"},
+		Scale:        int64(len(ids)),
+		ChunkSize:    chunk,
+		Workers:      1,
+		TempDir:      t.TempDir(),
+		KeepTempData: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results[0]
+}
+
+// readDuplicates reads a duplicates report into a map from ID to record.
+func readDuplicates(t *testing.T, path string) map[string]duplicateRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records := make(map[string]duplicateRecord)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec duplicateRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		records[rec.ID] = rec
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// numberedIDs returns n distinct IDs that sort in the order they are
+// listed.
+func numberedIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("id%05d", i)
+	}
+	return ids
+}
+
+// TestGenerateChunkParallel checks that workers splitting a chunk fill
+// every slot with a distinct ID from the generator.
+func TestGenerateChunkParallel(t *testing.T) {
+	script(numberedIDs(1000), 0)
+	gen, err := newGenerator("testseq", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, stats, err := generateChunk(context.Background(), gen, 1000, 3, chunkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.generated != 1000 || len(ids) != 1000 {
+		t.Fatalf("generated %d, %d IDs returned; want 1000", stats.generated, len(ids))
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, numberedIDs(1000)) {
+		t.Errorf("chunk does not hold every scripted ID exactly once")
+	}
+}
+
+// TestGenerateChunkDeadline stops rate-limited workers at a deadline long
+// before their ranges are full and checks that the gaps are closed: the
+// chunk holds exactly the IDs generated, and their sequence numbers moved
+// with them, so the global order of an increasing script stays intact.
+func TestGenerateChunkDeadline(t *testing.T) {
+	generated := script(numberedIDs(1000), 0)
+	gen, err := newGenerator("testseq", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, stats, err := generateChunk(context.Background(), gen, 1000, 4, chunkOptions{
+		bucket:     newTokenBucket(1000),
+		deadline:   time.Now().Add(50 * time.Millisecond),
+		trackOrder: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := generated()
+	if n == 0 || n >= 1000 || stats.generated != n || len(ids) != n {
+		t.Fatalf("generated %d (stats %d, %d IDs returned), want the same number between 0 and 1000", n, stats.generated, len(ids))
+	}
+	if stats.globalInversions != 0 || stats.longestGlobalDisorder != 0 {
+		t.Errorf("global inversions %d, longest disorder %d; want 0, 0", stats.globalInversions, stats.longestGlobalDisorder)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, numberedIDs(n)) {
+		t.Errorf("chunk is not the %d IDs generated: %v", n, ids)
+	}
+}
+
+// duplicateScript has two collisions inside a chunk (a in chunk 0, g in
+// chunk 2) and one between chunks (b in chunks 0 and 1) at 4 IDs per chunk.
+var duplicateScript = []string{"a", "b", "a", "c", "d", "b", "e", "f", "g", "g"}
+
+// TestRunDuplicateCounts checks that collisions inside a chunk and between
+// chunks are counted apart, and that the report lists every copy.
+func TestRunDuplicateCounts(t *testing.T) {
+	res := runScripted(t, duplicateScript, 4)
+	want := Result{Chunks: 3, Generated: 10, ChunkUnique: 8, Unique: 7, IntraChunkDuplicates: 2, Duplicates: 1, TotalDuplicates: 3}
+	if res.Chunks != want.Chunks || res.Generated != want.Generated || res.ChunkUnique != want.ChunkUnique ||
+		res.Unique != want.Unique || res.IntraChunkDuplicates != want.IntraChunkDuplicates ||
+		res.Duplicates != want.Duplicates || res.TotalDuplicates != want.TotalDuplicates {
+		t.Errorf("chunks %d, generated %d, chunk unique %d, unique %d, intra %d, cross %d, total %d; want %d, %d, %d, %d, %d, %d, %d",
+			res.Chunks, res.Generated, res.ChunkUnique, res.Unique, res.IntraChunkDuplicates, res.Duplicates, res.TotalDuplicates,
+			want.Chunks, want.Generated, want.ChunkUnique, want.Unique, want.IntraChunkDuplicates, want.Duplicates, want.TotalDuplicates)
+	}
+
+	records := readDuplicates(t, res.DuplicatesReport)
+	wantChunks := map[string][]int{"a": {0, 0}, "b": {0, 1}, "g": {2, 2}}
+	if len(records) != len(wantChunks) {
+		t.Errorf("report has %d IDs, want %d", len(records), len(wantChunks))
+	}
+	for id, chunks := range wantChunks {
+		rec := records[id]
+		if rec.Count != len(chunks) || len(rec.Occurrences) != len(chunks) {
+			t.Errorf("%s: count %d with %d occurrences, want %d", id, rec.Count, len(rec.Occurrences), len(chunks))
+			continue
+		}
+		got := make([]int, 0, len(rec.Occurrences))
+		for _, occ := range rec.Occurrences {
+			got = append(got, occ.Chunk)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, chunks) {
+			t.Errorf("%s: occurrences %+v, want chunks %v", id, rec.Occurrences, chunks)
+		}
+	}
+}
+
+// TestRunResume drops the last chunk of a finished run, as if it had been
+// interrupted, and checks that resuming generates only that chunk and
+// reproduces the totals of the full run.
+func TestRunResume(t *testing.T) {
+	full := runScripted(t, duplicateScript, 4)
+	man, err := loadManifest(full.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := man.Chunks[2]
+	man.Chunks = man.Chunks[:2]
+	if err := os.Remove(last.Path); err != nil {
+		t.Fatal(err)
+	}
+	if last.DuplicatesPath != "" {
+		if err := os.Remove(last.DuplicatesPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := saveManifest(full.OutputDir, man); err != nil {
+		t.Fatal(err)
+	}
+
+	// Continue the script where the kept chunks end.
+	generated := script(duplicateScript, 8)
+	results, err := Run(context.Background(), Config{ResumeDir: full.OutputDir, Workers: 1, KeepTempData: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := results[0]
+	if n := generated() - 8; n != 2 {
+		t.Errorf("resume generated %d IDs, want the 2 of the missing chunk", n)
+	}
+	if res.Chunks != full.Chunks || res.Generated != full.Generated || res.Unique != full.Unique ||
+		res.IntraChunkDuplicates != full.IntraChunkDuplicates || res.Duplicates != full.Duplicates {
+		t.Errorf("resumed run: chunks %d, generated %d, unique %d, intra %d, cross %d; full run: %d, %d, %d, %d, %d",
+			res.Chunks, res.Generated, res.Unique, res.IntraChunkDuplicates, res.Duplicates,
+			full.Chunks, full.Generated, full.Unique, full.IntraChunkDuplicates, full.Duplicates)
+	}
+	resumed, err := loadManifest(full.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resumed.Chunks[2]; got.Hash != last.Hash || got.DuplicatesHash != last.DuplicatesHash {
+		t.Errorf("regenerated chunk hashes %s/%s, want %s/%s", got.Hash, got.DuplicatesHash, last.Hash, last.DuplicatesHash)
+	}
+}
+
+// TestRunResumeKeepsDirectory checks that a resumed run without
+// KeepTempData leaves the directory it was given in place.
+func TestRunResumeKeepsDirectory(t *testing.T) {
+	full := runScripted(t, duplicateScript, 4)
+	script(duplicateScript, 0)
+	results, err := Run(context.Background(), Config{ResumeDir: full.OutputDir, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(full.ManifestPath); err != nil {
+		t.Errorf("resumed run directory: %v", err)
+	}
+	if results[0].OutputDir != full.OutputDir {
+		t.Errorf("resumed run reports directory %q, want %q", results[0].OutputDir, full.OutputDir)
+	}
+}
+
+// TestRunResumeRejectsModifiedChunk checks that a resumed run refuses a
+// chunk file that no longer matches its manifest hash.
+func TestRunResumeRejectsModifiedChunk(t *testing.T) {
+	full := runScripted(t, duplicateScript, 4)
+	man, err := loadManifest(full.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(man.Chunks[0].Path, []byte("a\nb\nz\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script(duplicateScript, 0)
+	if _, err := Run(context.Background(), Config{ResumeDir: full.OutputDir, Workers: 1, KeepTempData: true}); err == nil {
+		t.Error("resume accepted a modified chunk file")
+	}
+}
+
+// TestGlobalOrder replays IDs written by two workers in the order their
+// calls returned: a e | b c d f, where b, c and d sort before e.
+func TestGlobalOrder(t *testing.T) {
+	// Worker 0 wrote b, a, e and worker 1 c, d, f; seqs give the return order.
+	ids := []string{"b", "a", "e", "c", "d", "f"}
+	seqs := []int64{2, 0, 1, 3, 4, 5}
+	if inv, longest := globalOrder(ids, seqs); inv != 1 || longest != 3 {
+		t.Errorf("globalOrder = %d inversions, longest run %d; want 1 and 3", inv, longest)
+	}
+	if inv, longest := globalOrder([]string{"a", "b", "c"}, []int64{0, 1, 2}); inv != 0 || longest != 0 {
+		t.Errorf("globalOrder(sorted) = %d, %d; want 0, 0", inv, longest)
+	}
+	if inv, longest := globalOrder([]string{"a", "b", "c"}, []int64{2, 1, 0}); inv != 2 || longest != 2 {
+		t.Errorf("globalOrder(reversed) = %d, %d; want 2, 2", inv, longest)
+	}
+}
+
+// decodeFunc adapts a function to tools.TimeDecoder.
+type decodeFunc func(id string) (time.Time, error)
+
+func (f decodeFunc) DecodeTime(id string) (time.Time, error) { return f(id) }
+
+// TestTimeOrderViolations checks adjacent sorted IDs whose timestamps go
+// backwards, skipping IDs that do not decode.
+func TestTimeOrderViolations(t *testing.T) {
+	times := map[string]int64{"a": 1, "b": 3, "c": 2, "e": 1, "f": 5}
+	decoder := decodeFunc(func(id string) (time.Time, error) {
+		sec, ok := times[id]
+		if !ok {
+			return time.Time{}, errors.New("no timestamp")
+		}
+		return time.Unix(sec, 0), nil
+	})
+	// a-b and e-f are in order, b-c goes back, d breaks the chain.
+	checked, violations := timeOrderViolations([]string{"a", "b", "c", "d", "e", "f"}, decoder)
+	if checked != 3 || violations != 1 {
+		t.Errorf("timeOrderViolations = %d checked, %d violations; want 3 and 1", checked, violations)
+	}
+}
+
+// TestRunTrackOrder checks the order counts a run reports for a scripted
+// sequence that drops back below its maximum for three IDs.
+func TestRunTrackOrder(t *testing.T) {
+	script([]string{"a", "e", "b", "c", "d", "f"}, 0)
+	results, err := Run(context.Background(), Config{
+		Schemes:    []string{"testseq"},
+		Scale:      6,
+		Workers:    1,
+		TempDir:    t.TempDir(),
+		TrackOrder: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := results[0]
+	if !res.OrderTracked || res.OrderViolations != 1 || res.GlobalOrderViolations != 1 ||
+		res.LongestWorkerDisorder != 3 || res.LongestGlobalDisorder != 3 {
+		t.Errorf("tracked %t, violations %d, global %d, longest worker %d, longest global %d; want true, 1, 1, 3, 3",
+			res.OrderTracked, res.OrderViolations, res.GlobalOrderViolations, res.LongestWorkerDisorder, res.LongestGlobalDisorder)
+	}
+}
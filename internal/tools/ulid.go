@@ -1,9 +1,38 @@
 package tools
 
-import "github.com/oklog/ulid/v2"
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
 
 // GenerateULID 生成 ULID（Universally Unique Lexicographically Sortable Identifier）
 // 返回 26 字符的 ULID，包含时间戳和随机部分，支持时间排序
 func GenerateULID() string {
 	return ulid.Make().String()
 }
+
+// GenerateULIDAt 使用指定时间生成 ULID，同一毫秒内随机部分单调递增
+func GenerateULIDAt(t time.Time) string {
+	return ulid.MustNewDefault(t).String()
+}
+
+// DecodeULIDTime 解析 ULID 中嵌入的毫秒级时间戳
+func DecodeULIDTime(id string) (time.Time, error) {
+	u, err := ulid.ParseStrict(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ulid.Time(u.Time()), nil
+}
+
+// ExtractULIDRandom 返回 ULID 80 位随机部分的低 64 位
+// 同一毫秒内 ulid.Make 生成的随机部分单调递增，不是相互独立的随机数
+func ExtractULIDRandom(id string) (uint64, error) {
+	u, err := ulid.ParseStrict(id)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(u.Entropy()[2:]), nil
+}
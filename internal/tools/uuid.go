@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// UUID 按 RFC 9562 实现，只依赖标准库 crypto/rand
+// - UUIDv4：122 位随机数
+// - UUIDv7：48 位毫秒级 Unix 时间戳 + 74 位随机数，按时间字典序可排序
+// 字符串形式为 36 字符的小写十六进制（8-4-4-4-12）
+
+// uuidChars UUID 字符串使用的字符集
+const uuidChars = "0123456789abcdef-"
+
+// GenerateUUIDv4 生成随机 UUID（版本 4）
+func GenerateUUIDv4() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate random bytes: %v", err))
+	}
+	u[6] = (u[6] & 0x0F) | 0x40 // 版本 4
+	u[8] = (u[8] & 0x3F) | 0x80 // RFC 9562 变体
+	return formatUUID(u)
+}
+
+// GenerateUUIDv7 生成基于 Unix 毫秒时间戳的 UUID（版本 7）
+func GenerateUUIDv7() string {
+	return GenerateUUIDv7At(time.Now())
+}
+
+// GenerateUUIDv7At 使用指定时间生成 UUIDv7
+func GenerateUUIDv7At(t time.Time) string {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		panic(fmt.Sprintf("failed to generate random bytes: %v", err))
+	}
+	// 高 48 位：Unix 毫秒时间戳（大端序）
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(t.UnixMilli()))
+	copy(u[0:6], ts[2:8])
+	u[6] = (u[6] & 0x0F) | 0x70 // 版本 7
+	u[8] = (u[8] & 0x3F) | 0x80 // RFC 9562 变体
+	return formatUUID(u)
+}
+
+// DecodeUUIDv7Time 解析 UUIDv7 中嵌入的毫秒级时间戳
+func DecodeUUIDv7Time(id string) (time.Time, error) {
+	u, err := parseUUID(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if u[6]>>4 != 7 {
+		return time.Time{}, fmt.Errorf("uuid %q is version %d, want 7", id, u[6]>>4)
+	}
+	var ts [8]byte
+	copy(ts[2:8], u[0:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ts[:]))), nil
+}
+
+// ExtractUUIDv4Random 返回 UUIDv4 122 位随机数的低 64 位
+func ExtractUUIDv4Random(id string) (uint64, error) {
+	return extractUUIDRandom(id, 4)
+}
+
+// ExtractUUIDv7Random 返回 UUIDv7 74 位随机数（rand_a + rand_b）的低 64 位
+func ExtractUUIDv7Random(id string) (uint64, error) {
+	return extractUUIDRandom(id, 7)
+}
+
+// extractUUIDRandom 去掉版本号和变体位后拼接随机位，返回低 64 位
+// 低 64 位由第 7 字节的低 2 位、第 8 字节的低 6 位（去掉变体位）和最后 7 字节组成，v4 和 v7 相同
+func extractUUIDRandom(id string, version byte) (uint64, error) {
+	u, err := parseUUID(id)
+	if err != nil {
+		return 0, err
+	}
+	if u[6]>>4 != version {
+		return 0, fmt.Errorf("uuid %q is version %d, want %d", id, u[6]>>4, version)
+	}
+	var low [8]byte
+	copy(low[1:], u[9:16])
+	return uint64(u[7]&0x03)<<62 | uint64(u[8]&0x3F)<<56 | binary.BigEndian.Uint64(low[:]), nil
+}
+
+// formatUUID 将 16 字节编码为 8-4-4-4-12 格式的字符串
+func formatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], u[10:16])
+	return string(buf[:])
+}
+
+// parseUUID 解析 8-4-4-4-12 格式的 UUID 字符串
+func parseUUID(id string) ([16]byte, error) {
+	var u [16]byte
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		return u, fmt.Errorf("invalid uuid %q", id)
+	}
+	src := []byte(id[0:8] + id[9:13] + id[14:18] + id[19:23] + id[24:36])
+	if _, err := hex.Decode(u[:], src); err != nil {
+		return u, fmt.Errorf("invalid uuid %q: %w", id, err)
+	}
+	return u, nil
+}